@@ -0,0 +1,77 @@
+// Command accrual-worker опрашивает внешнюю accrual-систему отдельно от gophermart API
+// (chunk1-5): принимает заказы по gRPC (AccrualQueue.EnqueueOrder), сам решает, когда их
+// переопросить, и репортует результат обратно на API-сервер через AccrualQueue.ReportResult.
+// Выносится в свой процесс, чтобы всплески опроса не влияли на задержку пользовательских
+// запросов и чтобы воркер масштабировался независимо от API.
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/accrualpb"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/service"
+)
+
+// defaultWorkers - число горутин, одновременно опрашивающих accrual-систему; сопоставимо
+// с accrualWorkerPoolSize из cmd/gophermart/main.go для режима без отдельного воркера.
+const defaultWorkers = 5
+
+func main() {
+	_ = godotenv.Load()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		logger = zap.NewNop()
+	}
+	defer logger.Sync()
+
+	listenAddr := getenv("ACCRUAL_WORKER_ADDRESS", ":9091")
+	reportAddr := getenv("GOPHERMART_REPORT_ADDRESS", "localhost:9090")
+	accrualSystemAddr := os.Getenv("ACCRUAL_SYSTEM_ADDRESS")
+	if accrualSystemAddr == "" {
+		logger.Fatal("не задан ACCRUAL_SYSTEM_ADDRESS")
+	}
+
+	reportConn, err := grpc.NewClient(reportAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		logger.Fatal("не удалось создать gRPC-клиент к API-серверу", zap.Error(err))
+	}
+	reportClient := accrualpb.NewAccrualQueueClient(reportConn)
+
+	accrualClient := &service.HTTPAccrualClient{Client: &http.Client{Timeout: 5 * time.Second}, BaseURL: accrualSystemAddr}
+
+	queue := newQueueServer(defaultWorkers * 2)
+	p := newPoller(queue, accrualClient, reportClient, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.run(ctx, defaultWorkers)
+
+	lis, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		logger.Fatal("не удалось открыть порт для gRPC", zap.String("address", listenAddr), zap.Error(err))
+	}
+	grpcServer := grpc.NewServer()
+	accrualpb.RegisterAccrualQueueServer(grpcServer, queue)
+
+	logger.Info("accrual-worker запущен", zap.String("address", listenAddr), zap.String("report_address", reportAddr))
+	if err := grpcServer.Serve(lis); err != nil {
+		logger.Fatal("ошибка gRPC-сервера", zap.Error(err))
+	}
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/accrualpb"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/service"
+)
+
+// maxOrderAttempts - число попыток обращения к accrual-системе для одного заказа в рамках
+// одного прохода пуллера, прежде чем вернуть его в очередь и попробовать позже - см.
+// аналогичную константу в OrderService (до chunk1-5 опрос шёл прямо в API-процессе).
+const maxOrderAttempts = 3
+
+// poller - пул воркеров, опрашивающих внешнюю accrual-систему по заказам из queueServer.ready
+// и репортующих результат на API-сервер через accrualpb.AccrualQueueClient.ReportResult.
+type poller struct {
+	queue         *queueServer
+	accrualClient service.AccrualClient
+	reportClient  accrualpb.AccrualQueueClient
+	logger        *zap.Logger
+
+	throttleMu     sync.Mutex
+	throttledUntil time.Time
+}
+
+func newPoller(queue *queueServer, accrualClient service.AccrualClient, reportClient accrualpb.AccrualQueueClient, logger *zap.Logger) *poller {
+	return &poller{queue: queue, accrualClient: accrualClient, reportClient: reportClient, logger: logger}
+}
+
+func (p *poller) run(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go p.worker(ctx)
+	}
+}
+
+func (p *poller) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-p.queue.ready:
+			p.waitForThrottle(ctx)
+			p.processJob(ctx, j)
+		}
+	}
+}
+
+// processJob - аналог processOrder из OrderService до выноса в отдельный процесс: те же
+// backoff на транзиентные ошибки и троттлинг по Retry-After, но результат уходит по gRPC,
+// а не прямой записью в БД - ей владеет только API-сервер.
+func (p *poller) processJob(ctx context.Context, j job) {
+	backoff := 500 * time.Millisecond
+	for attempt := 1; attempt <= maxOrderAttempts; attempt++ {
+		orderCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		status, err := p.accrualClient.GetOrder(orderCtx, j.orderNumber)
+		cancel()
+		if err != nil {
+			p.logger.Error("Ошибка запроса к accrual-сервису",
+				zap.String("order", j.orderNumber), zap.Int("attempt", attempt), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			continue
+		}
+
+		if status.RetryAfter > 0 {
+			p.throttleUntil(status.RetryAfter)
+		}
+
+		switch status.Status {
+		case "REGISTERED", "PROCESSING", "":
+			p.report(ctx, j.orderID, "REGISTERED", nil)
+			p.queue.publish(&accrualpb.OrderUpdate{OrderId: j.orderID, Status: "REGISTERED"})
+			p.queue.requeueDelayed(j, jitter())
+		case "INVALID":
+			if err := p.report(ctx, j.orderID, "INVALID", nil); err != nil {
+				p.queue.requeueDelayed(j, jitter())
+				return
+			}
+			p.queue.publish(&accrualpb.OrderUpdate{OrderId: j.orderID, Status: "INVALID"})
+		case "PROCESSED":
+			// ReportResult здесь же проводит начисление по лицевому счёту (см.
+			// accrualreport.Server.ReportResult) - если оно не прошло, заказ остался
+			// не PROCESSED на стороне API, и его нужно обязательно повторить, иначе
+			// начисление потеряется молча: accrual-система отдаёт PROCESSED только раз.
+			if err := p.report(ctx, j.orderID, "PROCESSED", status.Accrual); err != nil {
+				p.queue.requeueDelayed(j, jitter())
+				return
+			}
+			p.queue.publish(&accrualpb.OrderUpdate{OrderId: j.orderID, Status: "PROCESSED", Accrual: status.Accrual})
+		default:
+			p.logger.Error("Неизвестный статус accrual-сервиса", zap.String("order", j.orderNumber), zap.String("status", status.Status))
+		}
+		return
+	}
+	p.logger.Error("Исчерпаны попытки обращения к accrual-сервису, заказ вернётся после задержки",
+		zap.String("order", j.orderNumber))
+	p.queue.requeueDelayed(j, jitter())
+}
+
+func (p *poller) report(ctx context.Context, orderID int64, status string, accrual *float64) error {
+	_, err := p.reportClient.ReportResult(ctx, &accrualpb.ReportResultRequest{
+		OrderId: orderID,
+		Status:  status,
+		Accrual: accrual,
+	})
+	if err != nil {
+		p.logger.Error("Ошибка отправки результата на API-сервер", zap.Int64("order_id", orderID), zap.Error(err))
+	}
+	return err
+}
+
+func jitter() time.Duration {
+	return time.Duration(1000+rand.Intn(2000)) * time.Millisecond
+}
+
+func (p *poller) throttleUntil(d time.Duration) {
+	p.throttleMu.Lock()
+	defer p.throttleMu.Unlock()
+	until := time.Now().Add(d)
+	if until.After(p.throttledUntil) {
+		p.throttledUntil = until
+	}
+}
+
+func (p *poller) waitForThrottle(ctx context.Context) {
+	for {
+		p.throttleMu.Lock()
+		wait := time.Until(p.throttledUntil)
+		p.throttleMu.Unlock()
+		if wait <= 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/accrualpb"
+)
+
+// job - заказ, принятый через EnqueueOrder и ожидающий опроса внешней accrual-системы.
+type job struct {
+	orderID     int64
+	orderNumber string
+	userID      int64
+}
+
+// queueServer реализует accrualpb.AccrualQueueServer.EnqueueOrder/WatchOrder. ReportResult
+// этому серверу не принадлежит (его обслуживает API-сервер, см. accrualreport.Server),
+// поэтому UnimplementedAccrualQueueServer отдаёт Unimplemented на этот метод по умолчанию.
+type queueServer struct {
+	accrualpb.UnimplementedAccrualQueueServer
+
+	ready chan job
+
+	subsMu sync.Mutex
+	subs   map[int64][]chan *accrualpb.OrderUpdate
+}
+
+func newQueueServer(buffer int) *queueServer {
+	return &queueServer{
+		ready: make(chan job, buffer),
+		subs:  make(map[int64][]chan *accrualpb.OrderUpdate),
+	}
+}
+
+func (s *queueServer) EnqueueOrder(ctx context.Context, req *accrualpb.EnqueueOrderRequest) (*accrualpb.EnqueueOrderResponse, error) {
+	select {
+	case s.ready <- job{orderID: req.OrderId, orderNumber: req.OrderNumber, userID: req.UserId}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &accrualpb.EnqueueOrderResponse{}, nil
+}
+
+// requeueDelayed возвращает заказ в очередь спустя delay - используется пуллером для
+// заказов в статусах REGISTERED/PROCESSING, которые ещё не готовы к выдаче начисления.
+func (s *queueServer) requeueDelayed(j job, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		s.ready <- j
+	})
+}
+
+func (s *queueServer) WatchOrder(req *accrualpb.WatchOrderRequest, stream accrualpb.AccrualQueue_WatchOrderServer) error {
+	updates := make(chan *accrualpb.OrderUpdate, 8)
+	s.subsMu.Lock()
+	s.subs[req.OrderId] = append(s.subs[req.OrderId], updates)
+	s.subsMu.Unlock()
+	defer s.unsubscribe(req.OrderId, updates)
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case u := <-updates:
+			if err := stream.Send(u); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *queueServer) unsubscribe(orderID int64, ch chan *accrualpb.OrderUpdate) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	subs := s.subs[orderID]
+	for i, c := range subs {
+		if c == ch {
+			s.subs[orderID] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(s.subs[orderID]) == 0 {
+		delete(s.subs, orderID)
+	}
+}
+
+// publish рассылает обновление статуса заказа всем активным подписчикам WatchOrder.
+// Подписчиков обычно нет (эндпоинт для отладки), поэтому публикация неблокирующая.
+func (s *queueServer) publish(u *accrualpb.OrderUpdate) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	for _, ch := range s.subs[u.OrderId] {
+		select {
+		case ch <- u:
+		default:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package accrualreport содержит серверную часть AccrualQueue, которую хостит сам
+// gophermart API (а не воркер) - только у API-сервера есть доступ к БД, нужный, чтобы
+// записать результат опроса accrual-системы (см. cmd/accrual-worker и requests.jsonl chunk1-5).
+package accrualreport
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/accrualpb"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/cache"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+)
+
+const (
+	orderStatusRegistered = "REGISTERED"
+	orderStatusProcessing = "PROCESSING"
+	orderStatusInvalid    = "INVALID"
+	orderStatusProcessed  = "PROCESSED"
+)
+
+// OrderRepo - подмножество db.OrderRepoPG, которое нужно серверу для записи результата
+// опроса одного заказа: обновить статус и, если начисление рассчитано, провести его в ledger.
+// GetOrderByID нужен, потому что ReportResultRequest несёт только order_id - счёт пользователя
+// для проводки узнаём по заказу, а не ждём user_id от воркера вторым каналом.
+type OrderRepo interface {
+	GetOrderByID(ctx context.Context, orderID int64) (*models.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID int64, status string) error
+	PostPostings(ctx context.Context, postings []models.Posting) error
+}
+
+// Server реализует accrualpb.AccrualQueueServer.ReportResult. EnqueueOrder и WatchOrder
+// этому серверу не принадлежат (их обслуживает воркер) - поэтому он встраивает
+// UnimplementedAccrualQueueServer и переопределяет только ReportResult.
+type Server struct {
+	accrualpb.UnimplementedAccrualQueueServer
+	OrderRepo OrderRepo
+	Logger    *zap.Logger
+	// Cache - опциональный кэш orders/balance (см. service.CachingOrderService). ReportResult
+	// меняет статус заказа в обход CachingOrderService, поэтому сбрасывает его ключи сам.
+	Cache cache.Cache
+}
+
+func NewServer(orderRepo OrderRepo, logger *zap.Logger, c cache.Cache) *Server {
+	return &Server{OrderRepo: orderRepo, Logger: logger, Cache: c}
+}
+
+func (s *Server) ReportResult(ctx context.Context, req *accrualpb.ReportResultRequest) (*accrualpb.ReportResultResponse, error) {
+	order, err := s.OrderRepo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Status {
+	case orderStatusRegistered, orderStatusProcessing, "":
+		if err := s.OrderRepo.UpdateOrderStatus(ctx, req.OrderId, orderStatusRegistered); err != nil {
+			return nil, err
+		}
+	case orderStatusInvalid:
+		if err := s.OrderRepo.UpdateOrderStatus(ctx, req.OrderId, orderStatusInvalid); err != nil {
+			return nil, err
+		}
+	case orderStatusProcessed:
+		if req.Accrual != nil {
+			orderID := req.OrderId
+			if err := s.OrderRepo.PostPostings(ctx, []models.Posting{
+				{AccountName: models.WorldAccountName, Amount: -*req.Accrual, OrderID: &orderID},
+				{AccountName: models.UserAccountName(order.UserID), Amount: *req.Accrual, OrderID: &orderID},
+			}); err != nil {
+				// Заказ намеренно остаётся не в PROCESSED: ошибка отдаётся воркеру, который
+				// повторит ReportResult позже (см. processJob в cmd/accrual-worker) - иначе
+				// заказ выпал бы из GetOrdersForStatusUpdate/GetOrdersForStatusUpdate-подобных
+				// выборок и начисление терялось бы навсегда молча, см. order_service.go::processOrder.
+				return nil, err
+			}
+		}
+		if err := s.OrderRepo.UpdateOrderStatus(ctx, req.OrderId, orderStatusProcessed); err != nil {
+			return nil, err
+		}
+	default:
+		s.Logger.Error("Неизвестный статус в ReportResult", zap.Int64("order_id", req.OrderId), zap.String("status", req.Status))
+	}
+
+	if s.Cache != nil {
+		_ = s.Cache.Del(ctx, fmt.Sprintf("orders:user:%d", order.UserID), fmt.Sprintf("balance:user:%d", order.UserID))
+	}
+	return &accrualpb.ReportResultResponse{}, nil
+}
@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache - кэш перед частыми, но редко меняющимися чтениями (заказы и баланс пользователя,
+// см. service.CachingOrderService). Redis-реализация используется при -redis-cache-addr,
+// NoopCache - по умолчанию и в тестах, чтобы кэш не нужно было мокать отдельно.
+type Cache interface {
+	// Get возвращает значение по ключу; found=false и пустая строка, если ключа нет или он истёк.
+	Get(ctx context.Context, key string) (value string, found bool, err error)
+	// Set кладёт значение по ключу с TTL.
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	// Del удаляет ключи по точному совпадению - используется, когда ключ известен целиком
+	// (например, balance:user:<id>).
+	Del(ctx context.Context, keys ...string) error
+	// Invalidate удаляет все ключи, подходящие под glob-паттерн (например, orders:user:<id>*).
+	Invalidate(ctx context.Context, pattern string) error
+}
@@ -0,0 +1,17 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// NoopCache ничего не хранит - используется, когда -redis-cache-addr не задан, чтобы
+// CachingOrderService можно было не оборачивать условно, а всегда вызывать через Cache.
+type NoopCache struct{}
+
+func (NoopCache) Get(ctx context.Context, key string) (string, bool, error) { return "", false, nil }
+func (NoopCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return nil
+}
+func (NoopCache) Del(ctx context.Context, keys ...string) error          { return nil }
+func (NoopCache) Invalidate(ctx context.Context, pattern string) error { return nil }
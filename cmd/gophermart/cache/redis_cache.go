@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache - реализация Cache поверх обычного Redis-клиента (не того же инстанса, что
+// очередь в пакете queue - это разные по характеру нагрузки данные, и отдельный адрес
+// позволяет разносить их по разным инстансам Redis при необходимости).
+type RedisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(addr string) *RedisCache {
+	return &RedisCache{client: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := c.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// Invalidate ищет ключи по glob-паттерну через SCAN (а не KEYS, чтобы не блокировать Redis
+// на больших базах) и удаляет их пачкой.
+func (c *RedisCache) Invalidate(ctx context.Context, pattern string) error {
+	var keys []string
+	iter := c.client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return c.Del(ctx, keys...)
+}
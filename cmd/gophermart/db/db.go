@@ -0,0 +1,19 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Init открывает соединение с PostgreSQL по переданному DSN и проверяет его пингом.
+func Init(dsn string) (*sql.DB, error) {
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Ping(); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
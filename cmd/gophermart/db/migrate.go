@@ -1,44 +1,70 @@
 package db
 
-import "database/sql"
-
-func Migrate(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			login TEXT UNIQUE NOT NULL,
-			password_hash TEXT NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+func newMigrate(db *sql.DB, fs embed.FS) (*migrate.Migrate, error) {
+	srcDriver, err := iofs.New(fs, "migrations")
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("не удалось открыть встроенные миграции: %w", err)
 	}
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS orders (
-			id SERIAL PRIMARY KEY,
-			order_number TEXT UNIQUE NOT NULL,
-			user_id INTEGER NOT NULL REFERENCES users(id),
-			status TEXT NOT NULL DEFAULT 'NEW',
-			accrual DOUBLE PRECISION,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
+	dbDriver, err := pgx.WithInstance(db, &pgx.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать драйвер миграций: %w", err)
+	}
+	return migrate.NewWithInstance("iofs", srcDriver, "pgx", dbDriver)
+}
+
+// MigrateUp поднимает схему БД до последней версии миграций из fs.
+func MigrateUp(db *sql.DB, fs embed.FS) error {
+	m, err := newMigrate(db, fs)
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS balance_transactions (
-			id SERIAL PRIMARY KEY,
-			user_id INTEGER NOT NULL REFERENCES users(id),
-			order_id INTEGER REFERENCES orders(id),
-			amount DOUBLE PRECISION NOT NULL,
-			type TEXT NOT NULL, -- 'ACCRUAL' или 'WITHDRAWAL'
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("ошибка применения миграций: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown откатывает схему БД на steps миграций назад.
+func MigrateDown(db *sql.DB, fs embed.FS, steps int) error {
+	m, err := newMigrate(db, fs)
 	if err != nil {
 		return err
 	}
+	if err := m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("ошибка отката миграций: %w", err)
+	}
 	return nil
 }
+
+// MigrateVersion возвращает текущую версию схемы и признак «грязного» состояния.
+func MigrateVersion(db *sql.DB, fs embed.FS) (version uint, dirty bool, err error) {
+	m, err := newMigrate(db, fs)
+	if err != nil {
+		return 0, false, err
+	}
+	return m.Version()
+}
+
+// MigrateForce принудительно выставляет версию схемы без применения миграций - используется
+// оператором, чтобы снять «грязный» флаг после ручного устранения последствий неудачной миграции.
+func MigrateForce(db *sql.DB, fs embed.FS, version int) error {
+	m, err := newMigrate(db, fs)
+	if err != nil {
+		return err
+	}
+	return m.Force(version)
+}
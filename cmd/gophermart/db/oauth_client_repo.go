@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+)
+
+type ClientRepoPG struct {
+	db *sql.DB
+}
+
+func NewClientRepoPG(db *sql.DB) *ClientRepoPG {
+	return &ClientRepoPG{db: db}
+}
+
+func (r *ClientRepoPG) CreateClient(ctx context.Context, client models.OAuthClient) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO clients (client_id, client_secret_hash, redirect_uris, allowed_scopes) VALUES ($1, $2, $3, $4)`,
+		client.ClientID, client.ClientSecretHash, pq.Array(client.RedirectURIs), pq.Array(client.AllowedScopes))
+	return err
+}
+
+func (r *ClientRepoPG) GetClientByID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var c models.OAuthClient
+	err := r.db.QueryRowContext(ctx,
+		`SELECT client_id, client_secret_hash, redirect_uris, allowed_scopes, created_at FROM clients WHERE client_id=$1`, clientID).
+		Scan(&c.ClientID, &c.ClientSecretHash, pq.Array(&c.RedirectURIs), pq.Array(&c.AllowedScopes), &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -3,8 +3,14 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
 )
 
@@ -30,6 +36,17 @@ func (r *OrderRepoPG) GetOrderByNumber(ctx context.Context, orderNumber string)
 	return &o, nil
 }
 
+// GetOrderByID нужен accrualreport.Server: ReportResult несёт только order_id, и счёт
+// пользователя для проводки начисления узнаётся по заказу, а не отдельным полем в RPC.
+func (r *OrderRepoPG) GetOrderByID(ctx context.Context, orderID int64) (*models.Order, error) {
+	var o models.Order
+	err := r.db.QueryRowContext(ctx, `SELECT id, order_number, user_id, status, created_at FROM orders WHERE id=$1`, orderID).Scan(&o.ID, &o.OrderNumber, &o.UserID, &o.Status, &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
 func (r *OrderRepoPG) GetOrderByNumberAndUserID(ctx context.Context, orderNumber string, userID int64) (*models.Order, error) {
 	var o models.Order
 	err := r.db.QueryRowContext(ctx, `SELECT id, order_number, user_id, status, created_at FROM orders WHERE order_number=$1 AND user_id=$2`, orderNumber, userID).Scan(&o.ID, &o.OrderNumber, &o.UserID, &o.Status, &o.CreatedAt)
@@ -39,18 +56,33 @@ func (r *OrderRepoPG) GetOrderByNumberAndUserID(ctx context.Context, orderNumber
 	return &o, nil
 }
 
-func (r *OrderRepoPG) GetOrdersByUserID(ctx context.Context, userID int64) ([]models.Order, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, order_number, user_id, status, created_at FROM orders WHERE user_id=$1 ORDER BY created_at DESC`, userID)
+// GetOrdersByUserID возвращает заказы пользователя вместе с начислением по каждому одним
+// JOIN-запросом - раньше начисление читалось отдельным GetOrderAccrual на заказ, что при
+// активном пользователе превращалось в N+1 к БД на каждый GET /api/user/orders.
+func (r *OrderRepoPG) GetOrdersByUserID(ctx context.Context, userID int64) ([]models.OrderWithAccrual, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT o.id, o.order_number, o.user_id, o.status, o.created_at,
+		       SUM(CASE WHEN la.name != $2 THEN p.amount END) AS accrual
+		FROM orders o
+		LEFT JOIN postings p ON p.order_id = o.id
+		LEFT JOIN ledger_accounts la ON la.id = p.account_id
+		WHERE o.user_id = $1
+		GROUP BY o.id
+		ORDER BY o.created_at DESC`, userID, models.WorldAccountName)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var orders []models.Order
+	var orders []models.OrderWithAccrual
 	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.OrderNumber, &o.UserID, &o.Status, &o.CreatedAt); err != nil {
+		var o models.OrderWithAccrual
+		var accrual sql.NullFloat64
+		if err := rows.Scan(&o.ID, &o.OrderNumber, &o.UserID, &o.Status, &o.CreatedAt, &accrual); err != nil {
 			return nil, err
 		}
+		if accrual.Valid {
+			o.Accrual = &accrual.Float64
+		}
 		orders = append(orders, o)
 	}
 	if err := rows.Err(); err != nil {
@@ -60,7 +92,7 @@ func (r *OrderRepoPG) GetOrdersByUserID(ctx context.Context, userID int64) ([]mo
 }
 
 func (r *OrderRepoPG) GetOrdersForStatusUpdate(ctx context.Context) ([]models.Order, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, order_number, user_id, status, created_at FROM orders WHERE status IN ('NEW', 'PROCESSING')`)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, order_number, user_id, status, created_at FROM orders WHERE status IN ('NEW', 'REGISTERED', 'PROCESSING')`)
 	if err != nil {
 		return nil, err
 	}
@@ -84,58 +116,216 @@ func (r *OrderRepoPG) UpdateOrderStatus(ctx context.Context, orderID int64, stat
 	return err
 }
 
-func (r *OrderRepoPG) AddBalanceTransaction(ctx context.Context, userID int64, orderID *int64, amount float64, txType string) error {
-	_, err := r.db.ExecContext(ctx, `INSERT INTO balance_transactions (user_id, order_id, amount, type) VALUES ($1, $2, $3, $4)`, userID, orderID, amount, txType)
-	return err
+// ensureLedgerAccount возвращает id счёта по имени, заводя строку в ledger_accounts,
+// если её ещё не было - так не нужно отдельно мигрировать схему при появлении новых
+// типов счетов (merchant:<orderNumber> заводится первым же списанием по этому заказу).
+func ensureLedgerAccount(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, `INSERT INTO ledger_accounts (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id`, name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRowContext(ctx, `SELECT id FROM ledger_accounts WHERE name=$1`, name).Scan(&id)
+	}
+	return id, err
 }
 
-func (r *OrderRepoPG) GetOrderAccrual(ctx context.Context, orderID int64) (*float64, error) {
-	var accrual sql.NullFloat64
-	err := r.db.QueryRowContext(ctx, `SELECT SUM(amount) FROM balance_transactions WHERE order_id=$1 AND type='ACCRUAL'`, orderID).Scan(&accrual)
-	if err != nil {
-		return nil, err
+// accountBalance суммирует проводки по счёту внутри транзакции tx - используется, чтобы
+// проверить достаточность средств до вставки новой проводки под той же блокировкой строки.
+func accountBalance(ctx context.Context, tx *sql.Tx, accountID int64) (float64, error) {
+	var balance float64
+	err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM postings WHERE account_id=$1`, accountID).Scan(&balance)
+	return balance, err
+}
+
+// PostPostings проводит набор postings одной транзакцией двойной записи: сумма amount
+// по всем проводкам должна быть равна нулю, иначе транзакция отклоняется. Затронутые счета
+// блокируются в порядке возрастания id (SELECT ... FOR UPDATE), чтобы параллельные проводки
+// по пересекающимся счетам не приводили к дедлоку, а списание со счёта пользователя не могло
+// увести баланс в минус. models.WorldAccountName - счёт бесконечного предложения (источник
+// начислений) и от проверки баланса намеренно освобождён, иначе первое же начисление упёрлось
+// бы в ErrInsufficientFunds.
+func (r *OrderRepoPG) PostPostings(ctx context.Context, postings []models.Posting) error {
+	var sum float64
+	for _, p := range postings {
+		sum += p.Amount
 	}
-	if !accrual.Valid {
-		return nil, nil
+	if absFloat(sum) > 0.0001 {
+		return fmt.Errorf("ledger: проводки не сбалансированы, сумма amount = %v", sum)
 	}
-	return &accrual.Float64, nil
+
+	txID := uuid.NewString()
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		accountIDs := make([]int64, len(postings))
+		for i, p := range postings {
+			id, err := ensureLedgerAccount(ctx, tx, p.AccountName)
+			if err != nil {
+				return err
+			}
+			accountIDs[i] = id
+		}
+
+		sortedIDs := append([]int64(nil), accountIDs...)
+		sort.Slice(sortedIDs, func(i, j int) bool { return sortedIDs[i] < sortedIDs[j] })
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM ledger_accounts WHERE id = ANY($1) ORDER BY id FOR UPDATE`, sortedIDs); err != nil {
+			return err
+		}
+
+		for i, p := range postings {
+			if p.Amount < 0 && p.AccountName != models.WorldAccountName {
+				balance, err := accountBalance(ctx, tx, accountIDs[i])
+				if err != nil {
+					return err
+				}
+				if balance+p.Amount < 0 {
+					return models.ErrInsufficientFunds
+				}
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO postings (tx_id, account_id, amount, order_id) VALUES ($1, $2, $3, $4)`,
+				txID, accountIDs[i], p.Amount, p.OrderID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (r *OrderRepoPG) GetUserBalance(ctx context.Context, userID int64) (current float64, withdrawn float64, err error) {
-	err = r.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(CASE WHEN type = 'ACCRUAL' THEN amount ELSE 0 END), 0) as accrual, COALESCE(SUM(CASE WHEN type = 'WITHDRAWAL' THEN amount ELSE 0 END), 0) as withdrawn FROM balance_transactions WHERE user_id = $1`, userID).Scan(&current, &withdrawn)
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(p.amount), 0),
+		       COALESCE(SUM(CASE WHEN p.amount < 0 THEN -p.amount ELSE 0 END), 0)
+		FROM postings p
+		JOIN ledger_accounts la ON la.id = p.account_id
+		WHERE la.name = $1`, models.UserAccountName(userID)).Scan(&current, &withdrawn)
+	return current, withdrawn, err
+}
+
+// ReconcileBalances проверяет инвариант двойной записи: проводки каждой транзакции
+// (сгруппированные по tx_id) должны суммироваться в ноль. Любое отклонение означает
+// повреждение данных и требует алерта - в отличие от кэшированного баланса из chunk0-4,
+// здесь балансы всегда считаются вживую из postings, так что расходиться им не с чем.
+func (r *OrderRepoPG) ReconcileBalances(ctx context.Context) ([]models.LedgerImbalance, error) {
+	const epsilon = 0.0001
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tx_id, SUM(amount) AS total
+		FROM postings
+		GROUP BY tx_id
+		HAVING ABS(SUM(amount)) > $1`, epsilon)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imbalances []models.LedgerImbalance
+	for rows.Next() {
+		var m models.LedgerImbalance
+		if err := rows.Scan(&m.TxID, &m.Sum); err != nil {
+			return nil, err
+		}
+		imbalances = append(imbalances, m)
 	}
-	current = current - withdrawn
-	return current, withdrawn, nil
+	return imbalances, rows.Err()
 }
 
-func (r *OrderRepoPG) GetUserWithdrawals(ctx context.Context, userID int64) ([]models.WithdrawalResponse, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT order_id, amount, created_at FROM balance_transactions WHERE user_id=$1 AND type='WITHDRAWAL' ORDER BY created_at DESC`, userID)
+func absFloat(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// GetAccountBalance возвращает текущий баланс именованного счёта ledger (world,
+// user:<id>:main, merchant:<orderNumber>) - используется обработчиком GET .../accounts/{account}/balance.
+func (r *OrderRepoPG) GetAccountBalance(ctx context.Context, accountName string) (float64, error) {
+	var balance float64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(p.amount), 0)
+		FROM postings p
+		JOIN ledger_accounts la ON la.id = p.account_id
+		WHERE la.name = $1`, accountName).Scan(&balance)
+	return balance, err
+}
+
+// GetLedgerTransactions возвращает проводки по именованному счёту с id больше afterID,
+// упорядоченные по id - клиент передаёт последний увиденный id как курсор пагинации.
+func (r *OrderRepoPG) GetLedgerTransactions(ctx context.Context, accountName string, afterID int64, limit int) ([]models.LedgerTransaction, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.id, p.tx_id, la.name, p.amount, p.order_id, p.created_at
+		FROM postings p
+		JOIN ledger_accounts la ON la.id = p.account_id
+		WHERE la.name = $1 AND p.id > $2
+		ORDER BY p.id ASC
+		LIMIT $3`, accountName, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var withdrawals []models.WithdrawalResponse
+	var txs []models.LedgerTransaction
 	for rows.Next() {
+		var t models.LedgerTransaction
 		var orderID sql.NullInt64
-		var sum float64
-		var processedAt sql.NullTime
-		if err := rows.Scan(&orderID, &sum, &processedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.TxID, &t.AccountName, &t.Amount, &orderID, &t.CreatedAt); err != nil {
 			return nil, err
 		}
-		orderNumber := ""
 		if orderID.Valid {
-			var num string
-			err = r.db.QueryRowContext(ctx, `SELECT order_number FROM orders WHERE id=$1`, orderID.Int64).Scan(&num)
-			if err == nil {
-				orderNumber = num
-			}
+			t.OrderID = &orderID.Int64
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// WithTx выполняет fn в рамках одной транзакции, откатывая её при ошибке или панике.
+func (r *OrderRepoPG) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithdrawAtomic списывает sum со счёта пользователя в пользу счёта магазина merchant:<orderNumber>
+// одной сбалансированной проводкой PostPostings, которая сама блокирует затронутые счета
+// и проверяет баланс - так два одновременных списания не могут увидеть один и тот же баланс
+// и провести счёт в минус.
+func (r *OrderRepoPG) WithdrawAtomic(ctx context.Context, userID int64, orderNumber string, sum float64) error {
+	return r.PostPostings(ctx, []models.Posting{
+		{AccountName: models.UserAccountName(userID), Amount: -sum},
+		{AccountName: models.MerchantAccountName(orderNumber), Amount: sum},
+	})
+}
+
+// merchantAccountPrefix - префикс имён merchant-счетов, см. models.MerchantAccountName.
+const merchantAccountPrefix = "merchant:"
+
+func (r *OrderRepoPG) GetUserWithdrawals(ctx context.Context, userID int64) ([]models.WithdrawalResponse, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT merchant.name, p.amount, p.created_at
+		FROM postings p
+		JOIN ledger_accounts user_account ON user_account.id = p.account_id
+		JOIN postings mp ON mp.tx_id = p.tx_id AND mp.id != p.id
+		JOIN ledger_accounts merchant ON merchant.id = mp.account_id AND merchant.name LIKE 'merchant:%'
+		WHERE user_account.name = $1 AND p.amount < 0
+		ORDER BY p.created_at DESC`, models.UserAccountName(userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var withdrawals []models.WithdrawalResponse
+	for rows.Next() {
+		var merchantAccount string
+		var amount float64
+		var createdAt time.Time
+		if err := rows.Scan(&merchantAccount, &amount, &createdAt); err != nil {
+			return nil, err
 		}
 		withdrawals = append(withdrawals, models.WithdrawalResponse{
-			Order:       orderNumber,
-			Sum:         sum,
-			ProcessedAt: processedAt.Time.Format(time.RFC3339),
+			Order:       strings.TrimPrefix(merchantAccount, merchantAccountPrefix),
+			Sum:         -amount,
+			ProcessedAt: createdAt.Format(time.RFC3339),
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+)
+
+// testDB поднимает соединение с PostgreSQL из TEST_DATABASE_URI и накатывает миграции -
+// тест пропускается, если переменная не выставлена, т.к. для него нужна реальная БД
+// (проверяется блокировка строк под нагрузкой, а не просто SQL-запрос).
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URI")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URI не задан, пропускаем интеграционный тест с реальной БД")
+	}
+	conn, err := Init(dsn)
+	if err != nil {
+		t.Fatalf("не удалось подключиться к БД: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := MigrateUp(conn, Migrations); err != nil {
+		t.Fatalf("не удалось применить миграции: %v", err)
+	}
+	return conn
+}
+
+// TestWithdrawAtomic_ConcurrentWithdrawalsNeverOverdraw запускает N одновременных списаний
+// с одного счёта на сумму, суммарно превышающую баланс, и проверяет, что итоговый баланс
+// никогда не уходит в минус - ровно та гонка, из-за которой WithdrawBalance стал атомарным
+// (блокировка строк счетов в PostPostings, см. WithdrawAtomic).
+func TestWithdrawAtomic_ConcurrentWithdrawalsNeverOverdraw(t *testing.T) {
+	conn := testDB(t)
+	repo := NewOrderRepoPG(conn)
+	ctx := context.Background()
+
+	userID := int64(900_000_000) + int64(os.Getpid())
+	const initialBalance = 1000.0
+	const withdrawal = 100.0
+	const attempts = 20 // 20 * 100 = 2000 > 1000 - заведомо больше баланса
+
+	if err := repo.PostPostings(ctx, []models.Posting{
+		{AccountName: models.WorldAccountName, Amount: -initialBalance},
+		{AccountName: models.UserAccountName(userID), Amount: initialBalance},
+	}); err != nil {
+		t.Fatalf("не удалось создать начальный баланс: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded int
+	var insufficientFunds int
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			orderNumber := fmt.Sprintf("withdraw-race-%d-%d", userID, i)
+			err := repo.WithdrawAtomic(ctx, userID, orderNumber, withdrawal)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, models.ErrInsufficientFunds):
+				insufficientFunds++
+			default:
+				t.Errorf("неожиданная ошибка списания: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if succeeded+insufficientFunds != attempts {
+		t.Fatalf("потеряны результаты списаний: succeeded=%d insufficientFunds=%d, ожидалось %d попыток",
+			succeeded, insufficientFunds, attempts)
+	}
+
+	current, _, err := repo.GetUserBalance(ctx, userID)
+	if err != nil {
+		t.Fatalf("не удалось получить баланс: %v", err)
+	}
+	if current < 0 {
+		t.Fatalf("баланс ушёл в минус: %v", current)
+	}
+	wantBalance := initialBalance - float64(succeeded)*withdrawal
+	if current != wantBalance {
+		t.Fatalf("баланс не соответствует числу успешных списаний: got=%v want=%v (succeeded=%d)", current, wantBalance, succeeded)
+	}
+}
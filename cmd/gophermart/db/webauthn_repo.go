@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+)
+
+type WebAuthnRepoPG struct {
+	db *sql.DB
+}
+
+func NewWebAuthnRepoPG(db *sql.DB) *WebAuthnRepoPG {
+	return &WebAuthnRepoPG{db: db}
+}
+
+func (r *WebAuthnRepoPG) CreateCredential(ctx context.Context, cred models.WebAuthnCredential) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, transports, aaguid)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		cred.UserID, cred.CredentialID, cred.PublicKey, cred.SignCount, pq.Array(cred.Transports), cred.AAGUID)
+	return err
+}
+
+func (r *WebAuthnRepoPG) GetCredentialsByUserID(ctx context.Context, userID int64) ([]models.WebAuthnCredential, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, credential_id, public_key, sign_count, transports, aaguid, created_at
+		 FROM webauthn_credentials WHERE user_id=$1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var creds []models.WebAuthnCredential
+	for rows.Next() {
+		var c models.WebAuthnCredential
+		if err := rows.Scan(&c.ID, &c.UserID, &c.CredentialID, &c.PublicKey, &c.SignCount, pq.Array(&c.Transports), &c.AAGUID, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
+
+func (r *WebAuthnRepoPG) GetCredentialByCredentialID(ctx context.Context, credentialID []byte) (*models.WebAuthnCredential, error) {
+	var c models.WebAuthnCredential
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, credential_id, public_key, sign_count, transports, aaguid, created_at
+		 FROM webauthn_credentials WHERE credential_id=$1`, credentialID).
+		Scan(&c.ID, &c.UserID, &c.CredentialID, &c.PublicKey, &c.SignCount, pq.Array(&c.Transports), &c.AAGUID, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *WebAuthnRepoPG) UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE webauthn_credentials SET sign_count=$1 WHERE credential_id=$2`, signCount, credentialID)
+	return err
+}
+
+func (r *WebAuthnRepoPG) SaveChallenge(ctx context.Context, sessionKey, login string, data []byte, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO webauthn_sessions (session_key, login, data, expires_at) VALUES ($1, $2, $3, $4)`,
+		sessionKey, login, data, expiresAt)
+	return err
+}
+
+func (r *WebAuthnRepoPG) GetChallenge(ctx context.Context, sessionKey string) (login string, data []byte, err error) {
+	err = r.db.QueryRowContext(ctx,
+		`SELECT login, data FROM webauthn_sessions WHERE session_key=$1 AND expires_at > NOW()`, sessionKey).
+		Scan(&login, &data)
+	return login, data, err
+}
+
+func (r *WebAuthnRepoPG) DeleteChallenge(ctx context.Context, sessionKey string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM webauthn_sessions WHERE session_key=$1`, sessionKey)
+	return err
+}
@@ -2,20 +2,47 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/accrualpb"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/accrualreport"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/cache"
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/config"
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/db"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/oauthserver"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/queue"
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/routers"
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/service"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
+// accrualWorkerPoolSize - число воркеров, одновременно опрашивающих accrual-сервис
+// в локальном режиме (без Redis).
+const accrualWorkerPoolSize = 5
+
 func main() {
 	_ = godotenv.Load()
 
+	// `gophermart migrate up|down [steps]|version|force <версия>` - ручной запуск миграций отдельно от старта сервера.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
+
+	// `gophermart reconcile` - проверка, что проводки каждой ledger-транзакции в postings суммируются в ноль.
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcileCommand()
+		return
+	}
+
 	logger, err := zap.NewProduction()
 	if err != nil {
 		log.Fatalf("Не удалось инициализировать zap logger: %v", err)
@@ -38,23 +65,186 @@ func main() {
 		dbConn.Close()
 	}()
 
-	if err := db.Migrate(dbConn); err != nil {
-		logger.Fatal("Ошибка миграции БД", zap.Error(err))
+	// -auto-migrate по умолчанию включён: реплика сама поднимает схему до актуальной версии
+	// при старте. Драйвер pgx для golang-migrate берёт postgres advisory lock на время Up/Down,
+	// так что при одновременном старте нескольких реплик миграции не гонятся друг с другом.
+	if cfg.AutoMigrate {
+		if err := db.MigrateUp(dbConn, db.Migrations); err != nil {
+			logger.Fatal("Ошибка миграции БД", zap.Error(err))
+		}
 	}
 
 	userRepo := db.NewUserRepoPG(dbConn)
 	userService := service.NewUserService(userRepo)
 	orderRepo := db.NewOrderRepoPG(dbConn)
-	orderService := service.NewOrderService(orderRepo, userRepo)
-	h := routers.NewHandler(userService, orderService, logger)
+	accrualClient := &service.HTTPAccrualClient{Client: &http.Client{Timeout: 5 * time.Second}, BaseURL: cfg.AccrualSystemAddress}
+
+	// Без настроенного Redis заказы опрашиваются локальным тикером (Queue == nil); с Redis -
+	// разбираются между репликами через общую очередь, чтобы не дублировать запросы к accrual-сервису.
+	var orderQueue queue.Queue
+	if cfg.RedisURL != "" {
+		orderQueue = queue.NewRedisQueue(cfg.RedisURL)
+	}
+	orderService := service.NewOrderService(orderRepo, userRepo, accrualClient, orderQueue)
+
+	// -redis-cache-addr включает кэш перед orders/balance (chunk1-6). Без него
+	// CachingOrderService работает с NoopCache - каждое чтение идёт прямо в БД, как раньше.
+	var readCache cache.Cache = cache.NoopCache{}
+	if cfg.RedisCacheAddr != "" {
+		readCache = cache.NewRedisCache(cfg.RedisCacheAddr)
+	}
+	orderService.Cache = readCache
+	cachingOrderService := service.NewCachingOrderService(orderService, readCache)
+
+	// -accrual-worker-address переключает опрос accrual-системы на отдельный процесс
+	// cmd/accrual-worker по gRPC (chunk1-5); без него поведение не меняется - однобинарный
+	// деплой продолжает опрашивать accrual-систему локальным тикером/воркерами ниже.
+	if cfg.AccrualWorkerAddress != "" {
+		accrualQueue, err := service.NewGRPCAccrualQueue(cfg.AccrualWorkerAddress)
+		if err != nil {
+			logger.Fatal("Ошибка подключения к accrual-worker", zap.Error(err))
+		}
+		orderService.AccrualQueue = accrualQueue
+	}
+
+	// WebAuthn включается только если задан RPID - без него сервис не знает, для какого домена
+	// проверять origin аутентификаторов, и выдавать passkey-эндпоинты небезопасно.
+	var webAuthnService *service.WebAuthnService
+	if cfg.WebAuthnRPID != "" {
+		webAuthnRepo := db.NewWebAuthnRepoPG(dbConn)
+		webAuthnService, err = service.NewWebAuthnService(cfg.WebAuthnRPID, "Gophermart", []string{cfg.WebAuthnRPOrigin}, webAuthnRepo, userRepo)
+		if err != nil {
+			logger.Fatal("Ошибка инициализации WebAuthn", zap.Error(err))
+		}
+	}
+
+	clientRepo := db.NewClientRepoPG(dbConn)
+	oauthSrv := oauthserver.NewServer(clientRepo)
+	oauthSrv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (string, error) {
+		login, ok := routers.GetOAuthLoginFromContext(r.Context())
+		if !ok {
+			return "", fmt.Errorf("пользователь не аутентифицирован")
+		}
+		return login, nil
+	})
+
+	h := routers.NewHandler(userService, cachingOrderService, logger, webAuthnService, oauthSrv)
 	r := routers.SetupRoutersWithLogger(h, logger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	orderService.StartOrderStatusWorker(ctx, cfg.AccrualSystemAddress, logger)
+	switch {
+	case orderService.AccrualQueue != nil:
+		// Опрос accrual-системы делегирован cmd/accrual-worker - свои воркеры/тикер не нужны,
+		// иначе один и тот же заказ опрашивался бы дважды.
+	case cfg.RedisURL != "":
+		orderService.StartQueueWorker(ctx, cfg.QueueWorkers, cfg.QueueVisibilityTimeout, logger)
+	default:
+		orderService.StartOrderStatusWorker(ctx, accrualWorkerPoolSize, logger)
+	}
+
+	// accrualreport.Server слушает отдельный gRPC-порт и принимает ReportResult от
+	// cmd/accrual-worker - поднимается независимо от режима выше, чтобы воркер мог
+	// репортовать результаты сразу после первого деплоя, до включения -accrual-worker-address.
+	if cfg.GRPCReportAddress != "" {
+		reportLis, err := net.Listen("tcp", cfg.GRPCReportAddress)
+		if err != nil {
+			logger.Fatal("Ошибка открытия порта для accrual-report gRPC", zap.Error(err))
+		}
+		grpcServer := grpc.NewServer()
+		accrualpb.RegisterAccrualQueueServer(grpcServer, accrualreport.NewServer(orderRepo, logger, readCache))
+		go func() {
+			logger.Info("accrual-report gRPC сервер запущен", zap.String("address", cfg.GRPCReportAddress))
+			if err := grpcServer.Serve(reportLis); err != nil {
+				logger.Error("Ошибка accrual-report gRPC сервера", zap.Error(err))
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			grpcServer.GracefulStop()
+		}()
+	}
 
 	logger.Info("Сервер запущен", zap.String("address", cfg.RunAddress))
 	if err := http.ListenAndServe(cfg.RunAddress, r); err != nil {
 		logger.Fatal("Ошибка запуска сервера", zap.Error(err))
 	}
 }
+
+// runMigrateCommand обрабатывает `gophermart migrate up` / `down [steps]` / `version` / `force <версия>`.
+func runMigrateCommand(args []string) {
+	cfg := config.New()
+	dbConn, err := db.Init(cfg.DatabaseURI)
+	if err != nil {
+		log.Fatalf("Ошибка подключения к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	if len(args) == 0 {
+		log.Fatal("использование: gophermart migrate up|down|version|force <версия>")
+	}
+
+	switch args[0] {
+	case "up":
+		if err := db.MigrateUp(dbConn, db.Migrations); err != nil {
+			log.Fatalf("Ошибка применения миграций: %v", err)
+		}
+		fmt.Println("миграции применены")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				log.Fatalf("неверное число шагов: %v", err)
+			}
+		}
+		if err := db.MigrateDown(dbConn, db.Migrations, steps); err != nil {
+			log.Fatalf("Ошибка отката миграций: %v", err)
+		}
+		fmt.Println("миграции откачены")
+	case "version":
+		version, dirty, err := db.MigrateVersion(dbConn, db.Migrations)
+		if err != nil {
+			log.Fatalf("Ошибка получения версии миграций: %v", err)
+		}
+		fmt.Printf("версия=%d грязная=%t\n", version, dirty)
+	case "force":
+		if len(args) < 2 {
+			log.Fatal("использование: gophermart migrate force <версия>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatalf("неверная версия: %v", err)
+		}
+		if err := db.MigrateForce(dbConn, db.Migrations, version); err != nil {
+			log.Fatalf("Ошибка принудительной установки версии: %v", err)
+		}
+		fmt.Println("версия миграций принудительно установлена")
+	default:
+		log.Fatalf("неизвестная подкоманда migrate: %s", args[0])
+	}
+}
+
+// runReconcileCommand проверяет, что проводки каждой ledger-транзакции суммируются в ноль,
+// и печатает обнаруженные несбалансированные tx_id.
+func runReconcileCommand() {
+	cfg := config.New()
+	dbConn, err := db.Init(cfg.DatabaseURI)
+	if err != nil {
+		log.Fatalf("Ошибка подключения к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	orderRepo := db.NewOrderRepoPG(dbConn)
+	imbalances, err := orderRepo.ReconcileBalances(context.Background())
+	if err != nil {
+		log.Fatalf("Ошибка сверки балансов: %v", err)
+	}
+	if len(imbalances) == 0 {
+		fmt.Println("расхождений не обнаружено")
+		return
+	}
+	for _, m := range imbalances {
+		fmt.Printf("tx_id=%s: сумма проводок = %.2f (ожидался 0)\n", m.TxID, m.Sum)
+	}
+}
@@ -1,6 +1,28 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInsufficientFunds - ошибка недостатка средств на счёте пользователя,
+// возвращаемая из репозитория при попытке списания внутри атомарной транзакции.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// WorldAccountName - счёт-источник для проводок без второй реальной стороны (начисление
+// баллов). Списания зачисляются не на него, а на счёт магазина, см. MerchantAccountName.
+const WorldAccountName = "world"
+
+// UserAccountName возвращает имя основного счёта пользователя в ledger_accounts.
+func UserAccountName(userID int64) string {
+	return fmt.Sprintf("user:%d:main", userID)
+}
+
+// MerchantAccountName возвращает имя счёта магазина, которому засчитывается списание по заказу.
+func MerchantAccountName(orderNumber string) string {
+	return fmt.Sprintf("merchant:%s", orderNumber)
+}
 
 type User struct {
 	ID           int64     `db:"id"`
@@ -16,6 +38,14 @@ type Order struct {
 	Status      string    `db:"status"`
 	CreatedAt   time.Time `db:"created_at"`
 }
+
+// OrderWithAccrual - заказ с уже посчитанным начислением, прочитанные одним JOIN-запросом
+// (см. OrderRepoPG.GetOrdersByUserID) - без этого GetOrdersHandler делал по отдельному
+// запросу GetOrderAccrual на каждый заказ пользователя.
+type OrderWithAccrual struct {
+	Order
+	Accrual *float64 `db:"accrual"`
+}
 type RegisterRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
@@ -28,13 +58,31 @@ type OrderResponse struct {
 	UploadedAt string   `json:"uploaded_at"`
 }
 
-type BalanceTransaction struct {
-	ID        int64     `db:"id"`
-	UserID    int64     `db:"user_id"`
-	OrderID   *int64    `db:"order_id"`
-	Amount    float64   `db:"amount"`
-	Type      string    `db:"type"`
-	CreatedAt time.Time `db:"created_at"`
+// Posting - одна строка проводки по именованному счёту (world, user:<id>:main,
+// merchant:<orderNumber>). Бизнес-событие оформляется набором Posting с общим tx_id,
+// где сумма amount по всем проводкам транзакции должна быть равна нулю.
+type Posting struct {
+	AccountName string
+	Amount      float64
+	OrderID     *int64
+}
+
+// LedgerTransaction - проводка, прочитанная из ledger, с собственным монотонно растущим ID -
+// по нему клиенты постранично читают историю через GET /api/user/ledger/transactions.
+type LedgerTransaction struct {
+	ID          int64     `db:"id"`
+	TxID        string    `db:"tx_id"`
+	AccountName string    `db:"account_name"`
+	Amount      float64   `db:"amount"`
+	OrderID     *int64    `db:"order_id"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// LedgerImbalance - транзакция, чьи проводки не суммируются в ноль. В здоровой системе
+// таких быть не должно; ReconcileBalances репортует их как признак повреждения данных.
+type LedgerImbalance struct {
+	TxID string
+	Sum  float64
 }
 
 type WithdrawalResponse struct {
@@ -42,3 +90,26 @@ type WithdrawalResponse struct {
 	Sum         float64 `json:"sum"`
 	ProcessedAt string  `json:"processed_at"`
 }
+
+// OAuthClient - сторонее приложение, которому можно выдать ограниченный по scope доступ
+// к заказам/балансу пользователя через OAuth2 (см. пакет oauthserver).
+type OAuthClient struct {
+	ClientID         string    `db:"client_id"`
+	ClientSecretHash string    `db:"client_secret_hash"`
+	RedirectURIs     []string  `db:"redirect_uris"`
+	AllowedScopes    []string  `db:"allowed_scopes"`
+	CreatedAt        time.Time `db:"created_at"`
+}
+
+// WebAuthnCredential - одна зарегистрированная пара ключей passkey пользователя.
+// SignCount используется для обнаружения клонированных аутентификаторов.
+type WebAuthnCredential struct {
+	ID           int64     `db:"id"`
+	UserID       int64     `db:"user_id"`
+	CredentialID []byte    `db:"credential_id"`
+	PublicKey    []byte    `db:"public_key"`
+	SignCount    uint32    `db:"sign_count"`
+	Transports   []string  `db:"transports"`
+	AAGUID       []byte    `db:"aaguid"`
+	CreatedAt    time.Time `db:"created_at"`
+}
@@ -0,0 +1,53 @@
+package oauthserver
+
+import (
+	"context"
+
+	goauth2 "github.com/go-oauth2/oauth2/v4"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+)
+
+// ClientRepo - часть db.ClientRepoPG, нужная стору; описана узким интерфейсом, как OrderRepo/UserRepo.
+type ClientRepo interface {
+	GetClientByID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+}
+
+// ClientStorePG реализует oauth2.ClientStore поверх таблицы clients.
+type ClientStorePG struct {
+	repo ClientRepo
+}
+
+func NewClientStorePG(repo ClientRepo) *ClientStorePG {
+	return &ClientStorePG{repo: repo}
+}
+
+func (s *ClientStorePG) GetByID(ctx context.Context, id string) (goauth2.ClientInfo, error) {
+	client, err := s.repo.GetClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &pgClientInfo{client: client}, nil
+}
+
+// pgClientInfo реализует goauth2.ClientInfo и goauth2.ClientPasswordVerifier. Секрет в БД
+// хранится как bcrypt-хэш, а не в сравнимом виде, поэтому GetSecret() всегда пуст -
+// manage.Manager (manager.go, проверка секрета клиента) видит, что ClientInfo реализует
+// ClientPasswordVerifier, и зовёт VerifyPassword вместо побайтового сравнения GetSecret().
+// Раньше секрет пытались провалидировать через ClientInfoHandler и протащить его обратно
+// через контекст запроса - но server.Server.HandleTokenRequest снимает ctx ДО вызова
+// ClientInfoHandler, так что контекст, который видел GetByID, никогда не содержал секрет,
+// и встроенная проверка библиотеки молча пропускала любой секрет.
+type pgClientInfo struct {
+	client *models.OAuthClient
+}
+
+func (c *pgClientInfo) GetID() string     { return c.client.ClientID }
+func (c *pgClientInfo) GetSecret() string { return "" }
+func (c *pgClientInfo) GetDomain() string { return "" }
+func (c *pgClientInfo) GetUserID() string { return "" }
+
+func (c *pgClientInfo) VerifyPassword(secret string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(c.client.ClientSecretHash), []byte(secret)) == nil
+}
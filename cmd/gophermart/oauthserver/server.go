@@ -0,0 +1,97 @@
+package oauthserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/manage"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"github.com/go-oauth2/oauth2/v4/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// NewServer собирает oauth2.Server поверх ClientStorePG и оперативного хранилища токенов
+// в памяти (токены короткоживущие, пережить рестарт не обязаны - аналогично webauthn-вызовам,
+// которые хранятся в БД только пока нужны приложению через HTTP-границу).
+func NewServer(clientRepo ClientRepo) *server.Server {
+	manager := manage.NewDefaultManager()
+	manager.MustTokenStorage(store.NewMemoryTokenStore())
+	manager.MapClientStorage(NewClientStorePG(clientRepo))
+
+	srv := server.NewDefaultServer(manager)
+	srv.SetClientInfoHandler(clientInfoHandler(clientRepo))
+	srv.SetClientScopeHandler(clientScopeHandler(clientRepo))
+	srv.SetInternalErrorHandler(func(err error) *errors.Response {
+		return &errors.Response{Error: err, StatusCode: http.StatusInternalServerError}
+	})
+	return srv
+}
+
+// clientInfoHandler сверяет присланный в запросе секрет с bcrypt-хэшем из БД и отклоняет
+// запрос здесь же - ClientPasswordVerifier на ClientInfo (см. client_store.go) сделал бы то же
+// самое для manage.Manager, но к тому моменту уже поздно: ClientInfoHandler возвращает
+// clientID, по которому строится весь дальнейший TokenGenerateRequest, и должен сам отказать
+// в невалидном секрете, а не полагаться на то, что кто-то ниже по стеку его перепроверит.
+func clientInfoHandler(clientRepo ClientRepo) func(r *http.Request) (string, string, error) {
+	return func(r *http.Request) (string, string, error) {
+		clientID, clientSecret, err := clientCredentialsFromRequest(r)
+		if err != nil {
+			return "", "", err
+		}
+
+		client, err := clientRepo.GetClientByID(r.Context(), clientID)
+		if err != nil {
+			return "", "", errors.ErrInvalidClient
+		}
+		if bcrypt.CompareHashAndPassword([]byte(client.ClientSecretHash), []byte(clientSecret)) != nil {
+			return "", "", errors.ErrInvalidClient
+		}
+
+		return clientID, clientSecret, nil
+	}
+}
+
+// clientScopeHandler отклоняет выдачу токена на scope, не входящий в AllowedScopes клиента -
+// без этого поле хранилось бы в БД (oauth_client_repo.go), но никогда не читалось, и любой
+// зарегистрированный клиент мог бы запросить произвольный scope.
+func clientScopeHandler(clientRepo ClientRepo) server.ClientScopeHandler {
+	return func(tgr *oauth2.TokenGenerateRequest) (bool, error) {
+		if tgr.Scope == "" {
+			return true, nil
+		}
+		client, err := clientRepo.GetClientByID(context.Background(), tgr.ClientID)
+		if err != nil {
+			return false, errors.ErrInvalidClient
+		}
+		for _, requested := range strings.Fields(tgr.Scope) {
+			if !containsScope(client.AllowedScopes, requested) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func containsScope(allowed []string, scope string) bool {
+	for _, s := range allowed {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func clientCredentialsFromRequest(r *http.Request) (string, string, error) {
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		return clientID, clientSecret, nil
+	}
+	clientID := r.FormValue("client_id")
+	clientSecret := r.FormValue("client_secret")
+	if clientID == "" {
+		return "", "", errors.ErrInvalidClient
+	}
+	return clientID, clientSecret, nil
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// LocalQueue - реализация Queue поверх буферизованного канала для однопроцессных
+// деплоев без Redis. EnqueueDelayed эмулирует отложенную постановку через time.AfterFunc.
+type LocalQueue struct {
+	ready chan string
+}
+
+func NewLocalQueue(buffer int) *LocalQueue {
+	return &LocalQueue{ready: make(chan string, buffer)}
+}
+
+func (q *LocalQueue) Enqueue(ctx context.Context, orderNumber string) error {
+	select {
+	case q.ready <- orderNumber:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *LocalQueue) EnqueueDelayed(ctx context.Context, orderNumber string, delay time.Duration) error {
+	time.AfterFunc(delay, func() {
+		_ = q.Enqueue(context.Background(), orderNumber)
+	})
+	return nil
+}
+
+// Dequeue ждёт задачу до отмены ctx: visibilityTimeout не используется, и ErrEmpty
+// эта реализация не возвращает.
+func (q *LocalQueue) Dequeue(ctx context.Context, _ time.Duration) (string, error) {
+	select {
+	case orderNumber := <-q.ready:
+		return orderNumber, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Ack - нет отдельного «processing»-списка, обрабатывать нечего.
+func (q *LocalQueue) Ack(ctx context.Context, orderNumber string) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrEmpty сигнализирует, что Dequeue не нашёл готовой к обработке задачи в пределах ожидания.
+var ErrEmpty = errors.New("queue: нет доступных задач")
+
+// Queue - очередь номеров заказов, ожидающих опроса accrual-сервиса. Позволяет нескольким
+// репликам gophermart делить работу между собой без дублирования запросов к одному заказу.
+type Queue interface {
+	// Enqueue ставит заказ в очередь на немедленную обработку.
+	Enqueue(ctx context.Context, orderNumber string) error
+	// EnqueueDelayed ставит заказ в очередь с задержкой - используется для повторной проверки
+	// ещё не рассчитанных (REGISTERED/PROCESSING) заказов.
+	EnqueueDelayed(ctx context.Context, orderNumber string, delay time.Duration) error
+	// Dequeue блокируется до готовой задачи или истечения visibilityTimeout и возвращает ErrEmpty,
+	// если задач не дождались. Заказ остаётся «в работе», пока не будет подтверждён через Ack.
+	Dequeue(ctx context.Context, visibilityTimeout time.Duration) (string, error)
+	// Ack подтверждает, что заказ, полученный через Dequeue, обработан и может быть снят с учёта.
+	Ack(ctx context.Context, orderNumber string) error
+}
@@ -0,0 +1,109 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	readyListKey        = "gophermart:orders:ready"
+	processingKey       = "gophermart:orders:processing"
+	delayedSetKey       = "gophermart:orders:delayed"
+	processingDeadlines = "gophermart:orders:processing:deadlines"
+)
+
+// RedisQueue хранит готовые к обработке заказы в списке (LPUSH/BRPOPLPUSH), а отложенные
+// повторные проверки - в отсортированном множестве, проиндексированном временем готовности.
+type RedisQueue struct {
+	client *redis.Client
+}
+
+func NewRedisQueue(addr string) *RedisQueue {
+	return &RedisQueue{client: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func (q *RedisQueue) Enqueue(ctx context.Context, orderNumber string) error {
+	return q.client.LPush(ctx, readyListKey, orderNumber).Err()
+}
+
+func (q *RedisQueue) EnqueueDelayed(ctx context.Context, orderNumber string, delay time.Duration) error {
+	score := float64(time.Now().Add(delay).Unix())
+	return q.client.ZAdd(ctx, delayedSetKey, redis.Z{Score: score, Member: orderNumber}).Err()
+}
+
+// PromoteDue переносит из delayedSetKey в readyListKey задачи, время которых уже наступило.
+// Его должен периодически дёргать фоновый воркер - в самом Redis нет таймеров.
+func (q *RedisQueue) PromoteDue(ctx context.Context) error {
+	now := fmt.Sprintf("%d", time.Now().Unix())
+	due, err := q.client.ZRangeByScore(ctx, delayedSetKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return err
+	}
+	for _, orderNumber := range due {
+		if removed, err := q.client.ZRem(ctx, delayedSetKey, orderNumber).Result(); err != nil || removed == 0 {
+			continue
+		}
+		if err := q.Enqueue(ctx, orderNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dequeue переносит заказ из readyListKey в processingKey и фиксирует дедлайн видимости
+// в processingDeadlines - по нему ReclaimStale находит заказы воркеров, упавших между
+// Dequeue и Ack, и возвращает их в очередь.
+func (q *RedisQueue) Dequeue(ctx context.Context, visibilityTimeout time.Duration) (string, error) {
+	orderNumber, err := q.client.BRPopLPush(ctx, readyListKey, processingKey, visibilityTimeout).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrEmpty
+	}
+	if err != nil {
+		return "", err
+	}
+	deadline := time.Now().Add(visibilityTimeout).Unix()
+	if err := q.client.HSet(ctx, processingDeadlines, orderNumber, deadline).Err(); err != nil {
+		return "", err
+	}
+	return orderNumber, nil
+}
+
+func (q *RedisQueue) Ack(ctx context.Context, orderNumber string) error {
+	if err := q.client.HDel(ctx, processingDeadlines, orderNumber).Err(); err != nil {
+		return err
+	}
+	return q.client.LRem(ctx, processingKey, 1, orderNumber).Err()
+}
+
+// ReclaimStale возвращает в readyListKey заказы, чей дедлайн видимости истёк - это значит,
+// что забравший их воркер упал или завис между Dequeue и Ack и никогда их не подтвердит.
+// HDel используется как точка эксклюзивности: если поле уже удалено (другой вызов ReclaimStale
+// или обычный Ack забрали его первыми), эта горутина не трогает запись повторно.
+func (q *RedisQueue) ReclaimStale(ctx context.Context, _ time.Duration) error {
+	deadlines, err := q.client.HGetAll(ctx, processingDeadlines).Result()
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for orderNumber, deadlineStr := range deadlines {
+		deadline, err := strconv.ParseInt(deadlineStr, 10, 64)
+		if err != nil || now < deadline {
+			continue
+		}
+		if removed, err := q.client.HDel(ctx, processingDeadlines, orderNumber).Result(); err != nil || removed == 0 {
+			continue
+		}
+		if err := q.client.LRem(ctx, processingKey, 1, orderNumber).Err(); err != nil {
+			return err
+		}
+		if err := q.Enqueue(ctx, orderNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/service"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-oauth2/oauth2/v4/server"
 	"go.uber.org/zap"
 )
 
@@ -21,21 +23,28 @@ type UserService interface {
 
 type OrderService interface {
 	UploadOrder(ctx context.Context, orderNumber string, userID int64) error
-	GetOrdersByUserID(ctx context.Context, userID int64) ([]models.Order, error)
-	GetOrderAccrual(ctx context.Context, orderID int64) (*float64, error)
+	GetOrdersByUserID(ctx context.Context, userID int64) ([]models.OrderWithAccrual, error)
 	GetUserBalance(ctx context.Context, userID int64) (float64, float64, error)
 	WithdrawBalance(ctx context.Context, userID int64, orderNumber string, sum float64) error
 	GetUserWithdrawals(ctx context.Context, userID int64) ([]models.WithdrawalResponse, error)
+	GetUserLedgerTransactions(ctx context.Context, userID int64, afterID int64, limit int) ([]models.LedgerTransaction, error)
+	GetAccountBalance(ctx context.Context, accountName string) (float64, error)
 }
 
 type Handler struct {
 	UserService  UserService
 	OrderService OrderService
 	Logger       *zap.Logger
+	// WebAuthnService - опциональный passkey-флоу, см. webauthn_handler.go. Если nil,
+	// соответствующие эндпоинты отвечают 501 - это включается только когда в конфиге задан RPID.
+	WebAuthnService *service.WebAuthnService
+	// OAuthServer - опциональный OAuth2-сервер авторизации, см. oauth_handler.go. Если nil,
+	// /oauth/* эндпоинты и OAuthMiddleware недоступны, cookie-флоу продолжает работать как раньше.
+	OAuthServer *server.Server
 }
 
-func NewHandler(userService *service.UserService, orderService *service.OrderService, logger *zap.Logger) *Handler {
-	return &Handler{UserService: userService, OrderService: orderService, Logger: logger}
+func NewHandler(userService *service.UserService, orderService OrderService, logger *zap.Logger, webAuthnService *service.WebAuthnService, oauthServer *server.Server) *Handler {
+	return &Handler{UserService: userService, OrderService: orderService, Logger: logger, WebAuthnService: webAuthnService, OAuthServer: oauthServer}
 }
 
 func (h *Handler) RegisterHandler() http.HandlerFunc {
@@ -146,14 +155,10 @@ func (h *Handler) GetOrdersHandler() http.HandlerFunc {
 		}
 		resp := make([]models.OrderResponse, 0, len(orders))
 		for _, o := range orders {
-			accrual, err := h.OrderService.GetOrderAccrual(r.Context(), o.ID)
-			if err != nil {
-				h.Logger.Error("Ошибка получения начисления для заказа", zap.Error(err))
-			}
 			resp = append(resp, models.OrderResponse{
 				Number:     o.OrderNumber,
 				Status:     o.Status,
-				Accrual:    accrual,
+				Accrual:    o.Accrual,
 				UploadedAt: o.CreatedAt.Format(time.RFC3339),
 			})
 		}
@@ -245,12 +250,66 @@ func (h *Handler) GetUserWithdrawalsHandler() http.HandlerFunc {
 	}
 }
 
+const defaultLedgerTransactionsLimit = 100
+
+func (h *Handler) GetLedgerTransactionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.getUserFromRequest(r)
+		if !ok {
+			http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+			return
+		}
+		afterID, _ := strconv.ParseInt(r.URL.Query().Get("after"), 10, 64)
+		txs, err := h.OrderService.GetUserLedgerTransactions(r.Context(), user.ID, afterID, defaultLedgerTransactionsLimit)
+		if err != nil {
+			http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(txs)
+	}
+}
+
+func (h *Handler) GetAccountBalanceHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.getUserFromRequest(r)
+		if !ok {
+			http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+			return
+		}
+		// {account} в пути оставлен для читаемости URL, но доверять ему нельзя - иначе
+		// любой аутентифицированный пользователь мог бы подобрать чужое или world имя счёта
+		// и прочитать его баланс. Счёт всегда берётся из токена/куки вызывающего.
+		accountName := models.UserAccountName(user.ID)
+		if chi.URLParam(r, "account") != accountName {
+			http.Error(w, "доступ к чужому счёту запрещён", http.StatusForbidden)
+			return
+		}
+		balance, err := h.OrderService.GetAccountBalance(r.Context(), accountName)
+		if err != nil {
+			http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		resp := struct {
+			Account string  `json:"account"`
+			Balance float64 `json:"balance"`
+		}{Account: accountName, Balance: balance}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
 func (h *Handler) getUserFromRequest(r *http.Request) (*models.User, bool) {
-	userIDStr, ok := GetUserIDFromContext(r.Context())
+	login, ok := GetOAuthLoginFromContext(r.Context())
 	if !ok {
-		return nil, false
+		login, ok = GetUserIDFromContext(r.Context())
+		if !ok {
+			return nil, false
+		}
 	}
-	user, err := h.UserService.GetUserByLogin(r.Context(), userIDStr)
+	user, err := h.UserService.GetUserByLogin(r.Context(), login)
 	if err != nil {
 		return nil, false
 	}
@@ -260,12 +319,22 @@ func (h *Handler) getUserFromRequest(r *http.Request) (*models.User, bool) {
 func SetupRoutersWithLogger(h *Handler, logger *zap.Logger) http.Handler {
 	r := chi.NewRouter()
 	r.Use(LoggingMiddleware(logger))
+	r.Use(RecoverMiddleware(logger))
 	r.Post("/api/user/register", h.RegisterHandler())
 	r.Post("/api/user/login", h.LoginHandler())
-	r.With(AuthMiddleware).Post("/api/user/orders", h.UploadOrderHandler())
-	r.With(AuthMiddleware).Get("/api/user/orders", h.GetOrdersHandler())
-	r.With(AuthMiddleware).Get("/api/user/balance", h.GetUserBalanceHandler())
-	r.With(AuthMiddleware).Post("/api/user/balance/withdraw", h.WithdrawBalanceHandler())
-	r.With(AuthMiddleware).Get("/api/user/withdrawals", h.GetUserWithdrawalsHandler())
+	r.With(h.OAuthMiddleware("orders:write")).Post("/api/user/orders", h.UploadOrderHandler())
+	r.With(h.OAuthMiddleware("orders:read")).Get("/api/user/orders", h.GetOrdersHandler())
+	r.With(h.OAuthMiddleware("balance:read")).Get("/api/user/balance", h.GetUserBalanceHandler())
+	r.With(h.OAuthMiddleware("withdrawals:write")).Post("/api/user/balance/withdraw", h.WithdrawBalanceHandler())
+	r.With(h.OAuthMiddleware("withdrawals:read")).Get("/api/user/withdrawals", h.GetUserWithdrawalsHandler())
+	r.With(h.OAuthMiddleware("balance:read")).Get("/api/user/ledger/transactions", h.GetLedgerTransactionsHandler())
+	r.With(h.OAuthMiddleware("balance:read")).Get("/api/user/ledger/accounts/{account}/balance", h.GetAccountBalanceHandler())
+	r.With(AuthMiddleware).Post("/api/user/webauthn/register/begin", h.WebAuthnRegisterBeginHandler())
+	r.With(AuthMiddleware).Post("/api/user/webauthn/register/finish", h.WebAuthnRegisterFinishHandler())
+	r.Post("/api/user/webauthn/login/begin", h.WebAuthnLoginBeginHandler())
+	r.Post("/api/user/webauthn/login/finish", h.WebAuthnLoginFinishHandler())
+	r.With(AuthMiddleware).Get("/oauth/authorize", h.OAuthAuthorizeHandler())
+	r.Post("/oauth/token", h.OAuthTokenHandler())
+	r.Post("/oauth/revoke", h.OAuthRevokeHandler())
 	return r
 }
@@ -1,19 +1,53 @@
 package routers
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+type requestIDKeyType struct{}
+
+var requestIDKey = requestIDKeyType{}
+
+// RequestIDFromContext возвращает request ID, проставленный LoggingMiddleware, если он есть.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok
+}
+
+// LoggingMiddleware проставляет X-Request-ID (принимает входящий заголовок или генерирует новый),
+// кладёт его в контекст запроса и на каждый запрос пишет одну структурированную access-log строку.
 func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			rw := &responseWriterWithStatus{ResponseWriter: w, status: 200}
+			requestID := r.Header.Get("X-Request-ID")
+			if requestID == "" {
+				requestID = uuid.NewString()
+			}
+			w.Header().Set("X-Request-ID", requestID)
+			ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+			r = r.WithContext(ctx)
+
+			start := time.Now()
+			rw := &responseWriterWithStatus{ResponseWriter: w, status: http.StatusOK}
 			next.ServeHTTP(rw, r)
-			if rw.status == http.StatusInternalServerError {
-				logger.Error("Внутренняя ошибка сервера", zap.String("url", r.URL.Path))
+
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", rw.status),
+				zap.Int64("duration_ms", time.Since(start).Milliseconds()),
+				zap.Int("bytes", rw.bytes),
+				zap.String("request_id", requestID),
+			}
+			if login, ok := GetUserIDFromContext(r.Context()); ok {
+				fields = append(fields, zap.String("login", login))
 			}
+			logger.Info("Запрос обработан", fields...)
 		})
 	}
 }
@@ -21,9 +55,16 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 type responseWriterWithStatus struct {
 	http.ResponseWriter
 	status int
+	bytes  int
 }
 
 func (rw *responseWriterWithStatus) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriterWithStatus) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
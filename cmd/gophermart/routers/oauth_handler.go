@@ -0,0 +1,91 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type oauthLoginKeyType struct{}
+
+var oauthLoginKey = oauthLoginKeyType{}
+
+// GetOAuthLoginFromContext возвращает логин пользователя, от имени которого выписан
+// предъявленный OAuth bearer-токен (см. OAuthMiddleware).
+func GetOAuthLoginFromContext(ctx context.Context) (string, bool) {
+	login, ok := ctx.Value(oauthLoginKey).(string)
+	return login, ok
+}
+
+// OAuthMiddleware - sibling для AuthMiddleware: если в запросе есть заголовок Authorization,
+// трактует его как OAuth2 bearer-токен, проверяет, что токен выдан под scope, и кладёт логин
+// владельца токена в контекст под тем же контрактом, что и cookie-флоу. Если заголовка нет,
+// запрос передаётся в обычный AuthMiddleware без изменений - существующий cookie-флоу не трогаем.
+func (h *Handler) OAuthMiddleware(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		cookieAuthorized := AuthMiddleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				cookieAuthorized.ServeHTTP(w, r)
+				return
+			}
+			tokenInfo, err := h.OAuthServer.ValidationBearerToken(r)
+			if err != nil {
+				http.Error(w, "неверный или истёкший токен", http.StatusUnauthorized)
+				return
+			}
+			if !scopeGranted(tokenInfo.GetScope(), scope) {
+				http.Error(w, "токену не хватает требуемого scope", http.StatusForbidden)
+				return
+			}
+			ctx := context.WithValue(r.Context(), oauthLoginKey, tokenInfo.GetUserID())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func scopeGranted(grantedScope, requiredScope string) bool {
+	for _, s := range strings.Fields(grantedScope) {
+		if s == requiredScope {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) OAuthAuthorizeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.getUserFromRequest(r)
+		if !ok {
+			http.Error(w, "требуется аутентификация пользователя", http.StatusUnauthorized)
+			return
+		}
+		err := h.OAuthServer.HandleAuthorizeRequest(w, r.WithContext(context.WithValue(r.Context(), oauthLoginKey, user.Login)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}
+}
+
+func (h *Handler) OAuthTokenHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.OAuthServer.HandleTokenRequest(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}
+}
+
+func (h *Handler) OAuthRevokeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenInfo, err := h.OAuthServer.ValidationBearerToken(r)
+		if err != nil {
+			http.Error(w, "неверный токен", http.StatusBadRequest)
+			return
+		}
+		if err := h.OAuthServer.Manager.RemoveAccessToken(r.Context(), tokenInfo.GetAccess()); err != nil {
+			http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,29 @@
+package routers
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// RecoverMiddleware перехватывает панику в обработчиках, логирует её вместе со стеком и request ID
+// и отвечает 500 вместо падения всего сервера (раньше панику ловил только defer recover в main).
+func RecoverMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					requestID, _ := RequestIDFromContext(r.Context())
+					logger.Error("Паника в обработчике запроса",
+						zap.Any("panic", rec),
+						zap.String("request_id", requestID),
+						zap.ByteString("stack", debug.Stack()),
+					)
+					http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package routers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/service"
+)
+
+const webAuthnSessionCookie = "webauthn_session"
+
+func (h *Handler) webAuthnUnavailable(w http.ResponseWriter) bool {
+	if h.WebAuthnService != nil {
+		return false
+	}
+	http.Error(w, "webauthn не настроен на этом сервере", http.StatusNotImplemented)
+	return true
+}
+
+func setWebAuthnSessionCookie(w http.ResponseWriter, sessionKey string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     webAuthnSessionCookie,
+		Value:    sessionKey,
+		Path:     "/",
+		Expires:  time.Now().Add(5 * time.Minute),
+		HttpOnly: true,
+	})
+}
+
+func clearWebAuthnSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: webAuthnSessionCookie, Value: "", Path: "/", MaxAge: -1})
+}
+
+func (h *Handler) WebAuthnRegisterBeginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.webAuthnUnavailable(w) {
+			return
+		}
+		user, ok := h.getUserFromRequest(r)
+		if !ok {
+			http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+			return
+		}
+		options, sessionKey, err := h.WebAuthnService.BeginRegistration(r.Context(), user.Login)
+		if err != nil {
+			http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		setWebAuthnSessionCookie(w, sessionKey)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(options)
+	}
+}
+
+func (h *Handler) WebAuthnRegisterFinishHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.webAuthnUnavailable(w) {
+			return
+		}
+		if _, ok := h.getUserFromRequest(r); !ok {
+			http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+			return
+		}
+		cookie, err := r.Cookie(webAuthnSessionCookie)
+		if err != nil {
+			http.Error(w, "не найдена сессия регистрации passkey", http.StatusBadRequest)
+			return
+		}
+		if err := h.WebAuthnService.FinishRegistration(r.Context(), cookie.Value, r); err != nil {
+			http.Error(w, "не удалось подтвердить регистрацию passkey", http.StatusBadRequest)
+			return
+		}
+		clearWebAuthnSessionCookie(w)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *Handler) WebAuthnLoginBeginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.webAuthnUnavailable(w) {
+			return
+		}
+		var req struct {
+			Login string `json:"login"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Login == "" {
+			http.Error(w, "неверный формат запроса", http.StatusBadRequest)
+			return
+		}
+		options, sessionKey, err := h.WebAuthnService.BeginLogin(r.Context(), req.Login)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrNoWebAuthnCredentials):
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+			default:
+				http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+			}
+			return
+		}
+		setWebAuthnSessionCookie(w, sessionKey)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(options)
+	}
+}
+
+func (h *Handler) WebAuthnLoginFinishHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.webAuthnUnavailable(w) {
+			return
+		}
+		cookie, err := r.Cookie(webAuthnSessionCookie)
+		if err != nil {
+			http.Error(w, "не найдена сессия входа по passkey", http.StatusBadRequest)
+			return
+		}
+		token, err := h.WebAuthnService.FinishLogin(r.Context(), cookie.Value, r)
+		if err != nil {
+			http.Error(w, "не удалось подтвердить вход по passkey", http.StatusUnauthorized)
+			return
+		}
+		clearWebAuthnSessionCookie(w)
+		http.SetCookie(w, &http.Cookie{
+			Name:     "jwt",
+			Value:    token,
+			Path:     "/",
+			Expires:  time.Now().Add(24 * time.Hour),
+			HttpOnly: true,
+		})
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -2,19 +2,80 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-// AccrualClient определяет интерфейс для обращения к внешней системе начислений (accrual service)
+// AccrualClient описывает обращение к внешней системе начислений (accrual service).
+// Реализация сама разбирает HTTP-ответ (включая 429 Too Many Requests), чтобы
+// сервисный слой управлял троттлингом, а не HTTP-прослойка.
+type AccrualClient interface {
+	GetOrder(ctx context.Context, orderNumber string) (*AccrualStatus, error)
+}
+
+// AccrualStatus - разобранный ответ accrual-сервиса: статус расчёта начисления,
+// сама сумма начисления (если есть) и время, на которое нужно приостановить
+// отправку новых запросов (заполняется из заголовка Retry-After при 429).
+type AccrualStatus struct {
+	Status     string
+	Accrual    *float64
+	RetryAfter time.Duration
+}
 
 type HTTPAccrualClient struct {
-	Client *http.Client
+	Client  *http.Client
+	BaseURL string
 }
 
-func (c *HTTPAccrualClient) GetOrder(ctx context.Context, url string) (*http.Response, error) {
+func (c *HTTPAccrualClient) GetOrder(ctx context.Context, orderNumber string) (*AccrualStatus, error) {
+	url := fmt.Sprintf("%s/api/orders/%s", c.BaseURL, orderNumber)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
-	return c.Client.Do(req)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return &AccrualStatus{Status: orderStatusInvalid}, nil
+	case http.StatusTooManyRequests:
+		return &AccrualStatus{
+			Status:     orderStatusRegistered,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}, nil
+	case http.StatusOK:
+		var body struct {
+			Order   string   `json:"order"`
+			Status  string   `json:"status"`
+			Accrual *float64 `json:"accrual,omitempty"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		return &AccrualStatus{Status: body.Status, Accrual: body.Accrual}, nil
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, fmt.Errorf("accrual-сервис временно недоступен: %s", resp.Status)
+	default:
+		return nil, fmt.Errorf("accrual-сервис вернул неожиданный статус: %s", resp.Status)
+	}
+}
+
+// parseRetryAfter разбирает заголовок Retry-After в виде числа секунд.
+// accrual-сервис не отдаёт его в HTTP-date формате, поэтому этот случай не обрабатываем.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
 }
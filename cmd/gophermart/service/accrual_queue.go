@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/accrualpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// AccrualQueueEnqueuer ставит заказ в очередь на опрос accrual-системы. GRPCAccrualQueue
+// реализует его поверх вынесенного в отдельный процесс cmd/accrual-worker (chunk1-5).
+// In-process реализации этого интерфейса нет: в однобинарных деплоях без воркера
+// OrderService.AccrualQueue остаётся nil, и заказы опрашивают воркеры самого OrderService
+// (StartOrderStatusWorker или StartQueueWorker).
+type AccrualQueueEnqueuer interface {
+	EnqueueOrder(ctx context.Context, orderID int64, orderNumber string, userID int64) error
+}
+
+// GRPCAccrualQueue - клиент AccrualQueue.EnqueueOrder к отдельному воркеру (cmd/accrual-worker).
+// OrderService.UploadOrder дергает его синхронно при загрузке заказа, так что задержка на
+// accrual-опрос больше не размазывается по пользовательскому запросу.
+type GRPCAccrualQueue struct {
+	client accrualpb.AccrualQueueClient
+}
+
+// NewGRPCAccrualQueue открывает соединение с воркером по addr (host:port). Соединение ленивое -
+// grpc.NewClient не блокируется на недоступном воркере, первый реальный вызов EnqueueOrder
+// вернёт ошибку, если воркер не поднят.
+func NewGRPCAccrualQueue(addr string) (*GRPCAccrualQueue, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCAccrualQueue{client: accrualpb.NewAccrualQueueClient(conn)}, nil
+}
+
+func (q *GRPCAccrualQueue) EnqueueOrder(ctx context.Context, orderID int64, orderNumber string, userID int64) error {
+	_, err := q.client.EnqueueOrder(ctx, &accrualpb.EnqueueOrderRequest{
+		OrderId:     orderID,
+		OrderNumber: orderNumber,
+		UserId:      userID,
+	})
+	return err
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/cache"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+)
+
+// cacheTTL - срок жизни кэшированных заказов/баланса. Короткий специально: свежесть важнее
+// экономии на чтениях, кэш здесь лишь снимает нагрузку с БД от активных пользователей,
+// а не служит источником истины.
+const cacheTTL = 5 * time.Second
+
+func ordersCacheKey(userID int64) string  { return fmt.Sprintf("orders:user:%d", userID) }
+func balanceCacheKey(userID int64) string { return fmt.Sprintf("balance:user:%d", userID) }
+
+type cachedBalance struct {
+	Current   float64 `json:"current"`
+	Withdrawn float64 `json:"withdrawn"`
+}
+
+// CachingOrderService оборачивает *OrderService Redis-кэшем перед GetOrdersByUserID и
+// GetUserBalance - GetOrdersHandler раньше делал отдельный запрос к БД на каждый заказ,
+// а баланс читается почти на каждый запрос кошелька. Остальные методы наследуются от
+// встроенного *OrderService без изменений.
+type CachingOrderService struct {
+	*OrderService
+	Cache cache.Cache
+}
+
+func NewCachingOrderService(orderService *OrderService, c cache.Cache) *CachingOrderService {
+	return &CachingOrderService{OrderService: orderService, Cache: c}
+}
+
+func (s *CachingOrderService) GetOrdersByUserID(ctx context.Context, userID int64) ([]models.OrderWithAccrual, error) {
+	key := ordersCacheKey(userID)
+	if cached, found, err := s.Cache.Get(ctx, key); err == nil && found {
+		var orders []models.OrderWithAccrual
+		if err := json.Unmarshal([]byte(cached), &orders); err == nil {
+			return orders, nil
+		}
+	}
+
+	orders, err := s.OrderService.GetOrdersByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if encoded, err := json.Marshal(orders); err == nil {
+		_ = s.Cache.Set(ctx, key, string(encoded), cacheTTL)
+	}
+	return orders, nil
+}
+
+func (s *CachingOrderService) GetUserBalance(ctx context.Context, userID int64) (current float64, withdrawn float64, err error) {
+	key := balanceCacheKey(userID)
+	if cached, found, err := s.Cache.Get(ctx, key); err == nil && found {
+		var b cachedBalance
+		if err := json.Unmarshal([]byte(cached), &b); err == nil {
+			return b.Current, b.Withdrawn, nil
+		}
+	}
+
+	current, withdrawn, err = s.OrderService.GetUserBalance(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if encoded, err := json.Marshal(cachedBalance{Current: current, Withdrawn: withdrawn}); err == nil {
+		_ = s.Cache.Set(ctx, key, string(encoded), cacheTTL)
+	}
+	return current, withdrawn, nil
+}
+
+func (s *CachingOrderService) UploadOrder(ctx context.Context, orderNumber string, userID int64) error {
+	if err := s.OrderService.UploadOrder(ctx, orderNumber, userID); err != nil {
+		return err
+	}
+	s.invalidateUser(ctx, userID)
+	return nil
+}
+
+func (s *CachingOrderService) WithdrawBalance(ctx context.Context, userID int64, orderNumber string, sum float64) error {
+	if err := s.OrderService.WithdrawBalance(ctx, userID, orderNumber, sum); err != nil {
+		return err
+	}
+	s.invalidateUser(ctx, userID)
+	return nil
+}
+
+func (s *CachingOrderService) invalidateUser(ctx context.Context, userID int64) {
+	_ = s.Cache.Del(ctx, ordersCacheKey(userID), balanceCacheKey(userID))
+}
@@ -2,43 +2,73 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"net/http"
+	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/cache"
 	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/queue"
+)
+
+const (
+	orderStatusNew        = "NEW"
+	orderStatusRegistered = "REGISTERED"
+	orderStatusProcessing = "PROCESSING"
+	orderStatusProcessed  = "PROCESSED"
+	orderStatusInvalid    = "INVALID"
 )
 
 type OrderRepo interface {
 	CreateOrder(ctx context.Context, orderNumber string, userID int64) error
 	GetOrderByNumber(ctx context.Context, orderNumber string) (*models.Order, error)
 	GetOrderByNumberAndUserID(ctx context.Context, orderNumber string, userID int64) (*models.Order, error)
-	GetOrdersByUserID(ctx context.Context, userID int64) ([]models.Order, error)
+	GetOrdersByUserID(ctx context.Context, userID int64) ([]models.OrderWithAccrual, error)
 	GetOrdersForStatusUpdate(ctx context.Context) ([]models.Order, error)
 	UpdateOrderStatus(ctx context.Context, orderID int64, status string) error
-	AddBalanceTransaction(ctx context.Context, userID int64, orderID *int64, amount float64, txType string) error
-	GetOrderAccrual(ctx context.Context, orderID int64) (*float64, error)
+	PostPostings(ctx context.Context, postings []models.Posting) error
 	GetUserBalance(ctx context.Context, userID int64) (current float64, withdrawn float64, err error)
+	WithdrawAtomic(ctx context.Context, userID int64, orderNumber string, sum float64) error
 	GetUserWithdrawals(ctx context.Context, userID int64) ([]models.WithdrawalResponse, error)
-}
-
-type AccrualClient interface {
-	GetOrder(ctx context.Context, url string) (*http.Response, error)
+	GetAccountBalance(ctx context.Context, accountName string) (float64, error)
+	GetLedgerTransactions(ctx context.Context, accountName string, afterID int64, limit int) ([]models.LedgerTransaction, error)
 }
 
 type OrderService struct {
 	OrderRepo     OrderRepo
 	UserRepo      UserRepo
 	AccrualClient AccrualClient
+	// Queue - опциональная распределённая очередь (см. пакет queue). Если nil, сервис опрашивает
+	// БД локальным тикером (StartOrderStatusWorker); если задана - заказы разбираются между
+	// репликами через неё (StartQueueWorker), и каждая реплика не дублирует чужую работу.
+	Queue queue.Queue
+	// AccrualQueue - опциональный вынос опроса accrual-системы в отдельный процесс
+	// cmd/accrual-worker по gRPC (см. GRPCAccrualQueue). Если задан, UploadOrder отдаёт заказ
+	// ему и не трогает Queue/воркеры ниже - они остаются для деплоев без отдельного воркера.
+	AccrualQueue AccrualQueueEnqueuer
+	// Cache - опциональный кэш перед orders/balance (см. CachingOrderService). Сам OrderService
+	// кэш не читает - он только инвалидирует ключи пользователя, когда воркер ниже меняет
+	// статус/начисление заказа, чего CachingOrderService не видит (это происходит в фоне,
+	// а не внутри вызовов, которые она оборачивает).
+	Cache cache.Cache
+
+	throttleMu     sync.Mutex
+	throttledUntil time.Time
+
+	// inFlightOrders - id заказов, на которые прямо сейчас есть запущенный processOrder
+	// (см. StartOrderStatusWorker). Статус заказа в БД не меняется, пока не придёт ответ
+	// accrual-сервиса, поэтому он всё это время продолжает попадать в выборку
+	// GetOrdersForStatusUpdate - без этой отметки второй тикер или requeue() отправили бы
+	// тот же заказ второму воркеру, и оба в конце концов могли бы провести начисление дважды.
+	inFlightOrders sync.Map
 }
 
-func NewOrderService(orderRepo OrderRepo, userRepo UserRepo, accrualClient AccrualClient) *OrderService {
-	return &OrderService{OrderRepo: orderRepo, UserRepo: userRepo, AccrualClient: accrualClient}
+func NewOrderService(orderRepo OrderRepo, userRepo UserRepo, accrualClient AccrualClient, q queue.Queue) *OrderService {
+	return &OrderService{OrderRepo: orderRepo, UserRepo: userRepo, AccrualClient: accrualClient, Queue: q}
 }
 
 var (
@@ -94,20 +124,58 @@ func (s *OrderService) UploadOrder(ctx context.Context, orderNumber string, user
 	if err != nil {
 		return err
 	}
+	if s.AccrualQueue != nil {
+		created, err := s.OrderRepo.GetOrderByNumber(ctx, orderNumber)
+		if err != nil {
+			return err
+		}
+		return s.AccrualQueue.EnqueueOrder(ctx, created.ID, orderNumber, userID)
+	}
+	if s.Queue != nil {
+		return s.Queue.Enqueue(ctx, orderNumber)
+	}
 	return nil
 }
 
-func (s *OrderService) GetOrdersByUserID(ctx context.Context, userID int64) ([]models.Order, error) {
+func (s *OrderService) GetOrdersByUserID(ctx context.Context, userID int64) ([]models.OrderWithAccrual, error) {
 	return s.OrderRepo.GetOrdersByUserID(ctx, userID)
 }
 
-func (s *OrderService) StartOrderStatusWorker(ctx context.Context, accrualAddr string, logger *zap.Logger) {
+// pollInterval - период опроса БД на предмет вновь загруженных заказов (статус NEW).
+// Заказы в статусах REGISTERED/PROCESSING сами себя переставляют в очередь с джиттером
+// и не дожидаются этого тикера.
+const pollInterval = 2 * time.Second
+
+// maxOrderAttempts - число попыток обращения к accrual-сервису для одного заказа в рамках
+// одного прохода воркера, прежде чем передать его в requeue для повторной проверки позже.
+const maxOrderAttempts = 3
+
+// StartOrderStatusWorker запускает пул из workers воркеров, которые забирают заказы
+// из общего канала и опрашивают accrual-сервис. Воркеры уважают Retry-After из 429:
+// при получении этого статуса диспетчеризация новых запросов приостанавливается для
+// всех воркеров до истечения окна, общего для всех воркеров времени.
+func (s *OrderService) StartOrderStatusWorker(ctx context.Context, workers int, logger *zap.Logger) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan models.Order, workers*2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runOrderWorker(ctx, jobs, logger)
+		}()
+	}
+
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(pollInterval)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
+				wg.Wait()
 				logger.Info("Order status worker stopped")
 				return
 			case <-ticker.C:
@@ -117,44 +185,239 @@ func (s *OrderService) StartOrderStatusWorker(ctx context.Context, accrualAddr s
 					continue
 				}
 				for _, order := range orders {
-					url := fmt.Sprintf("%s/api/orders/%s", accrualAddr, order.OrderNumber)
-					orderCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-					defer cancel()
-					resp, err := s.AccrualClient.GetOrder(orderCtx, url)
-					if err != nil {
-						logger.Error("Ошибка запроса к accrual-сервису", zap.Error(err))
-						continue
-					}
-					defer resp.Body.Close()
-					if resp.StatusCode == http.StatusNoContent {
-						_ = s.OrderRepo.UpdateOrderStatus(ctx, order.ID, "INVALID")
-						continue
-					}
-					if resp.StatusCode != http.StatusOK {
-						logger.Error("Неожиданный статус accrual-сервиса", zap.String("status", resp.Status))
-						continue
+					s.dispatchOrder(order, jobs)
+				}
+			}
+		}
+	}()
+}
+
+// dispatchOrder отправляет заказ в jobs, только если для него ещё нет запущенного
+// processOrder (см. inFlightOrders) - без этого ровно тот же заказ, который уже обрабатывается
+// одним воркером, на следующем тике отправился бы второму, и оба могли бы в итоге провести
+// начисление по одному и тому же заказу.
+func (s *OrderService) dispatchOrder(order models.Order, jobs chan models.Order) {
+	if _, alreadyInFlight := s.inFlightOrders.LoadOrStore(order.ID, struct{}{}); alreadyInFlight {
+		return
+	}
+	select {
+	case jobs <- order:
+	default:
+		// канал переполнен - заказ подхватится на следующем тике
+		s.inFlightOrders.Delete(order.ID)
+	}
+}
+
+func (s *OrderService) runOrderWorker(ctx context.Context, jobs chan models.Order, logger *zap.Logger) {
+	requeue := func(order models.Order) {
+		jitter := time.Duration(1000+rand.Intn(2000)) * time.Millisecond
+		time.AfterFunc(jitter, func() {
+			select {
+			case <-ctx.Done():
+				s.inFlightOrders.Delete(order.ID)
+			case jobs <- order:
+			}
+		})
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case order := <-jobs:
+			s.waitForThrottle(ctx)
+			if !s.processOrder(ctx, order, requeue, logger) {
+				s.inFlightOrders.Delete(order.ID)
+			}
+		}
+	}
+}
+
+// delayedPromoter - реализации Queue, хранящие отложенные заказы отдельно (например, в ZSET Redis),
+// реализуют этот интерфейс, чтобы StartQueueWorker мог периодически переносить готовые заказы в очередь.
+type delayedPromoter interface {
+	PromoteDue(ctx context.Context) error
+}
+
+// staleReclaimer - реализации Queue, отслеживающие дедлайн видимости выданных Dequeue заказов
+// (например, Redis), реализуют этот интерфейс, чтобы StartQueueWorker мог периодически
+// возвращать в очередь заказы воркеров, упавших между Dequeue и Ack.
+type staleReclaimer interface {
+	ReclaimStale(ctx context.Context, visibilityTimeout time.Duration) error
+}
+
+// StartQueueWorker запускает пул воркеров, читающих номера заказов из распределённой очереди
+// s.Queue (например, Redis) вместо локального опроса БД - так несколько реплик gophermart
+// делят заказы между собой, а не опрашивают accrual-сервис по одному и тому же заказу хором.
+func (s *OrderService) StartQueueWorker(ctx context.Context, workers int, visibilityTimeout time.Duration, logger *zap.Logger) {
+	if workers < 1 {
+		workers = 1
+	}
+	if promoter, ok := s.Queue.(delayedPromoter); ok {
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := promoter.PromoteDue(ctx); err != nil {
+						logger.Error("Ошибка переноса отложенных заказов в очередь", zap.Error(err))
 					}
-					var accrualResp struct {
-						Order   string   `json:"order"`
-						Status  string   `json:"status"`
-						Accrual *float64 `json:"accrual,omitempty"`
+				}
+			}
+		}()
+	}
+	if reclaimer, ok := s.Queue.(staleReclaimer); ok {
+		go func() {
+			ticker := time.NewTicker(visibilityTimeout)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := reclaimer.ReclaimStale(ctx, visibilityTimeout); err != nil {
+						logger.Error("Ошибка возврата зависших заказов в очередь", zap.Error(err))
 					}
-					if err := json.NewDecoder(resp.Body).Decode(&accrualResp); err != nil {
-						logger.Error("Ошибка декодирования ответа accrual", zap.Error(err))
+				}
+			}
+		}()
+	}
+	requeue := func(order models.Order) {
+		jitter := time.Duration(1000+rand.Intn(2000)) * time.Millisecond
+		if err := s.Queue.EnqueueDelayed(ctx, order.OrderNumber, jitter); err != nil {
+			logger.Error("Ошибка отложенной постановки заказа в очередь", zap.String("order", order.OrderNumber), zap.Error(err))
+		}
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				if ctx.Err() != nil {
+					return
+				}
+				orderNumber, err := s.Queue.Dequeue(ctx, visibilityTimeout)
+				if err != nil {
+					if errors.Is(err, queue.ErrEmpty) || errors.Is(err, context.Canceled) {
 						continue
 					}
-					_ = s.OrderRepo.UpdateOrderStatus(ctx, order.ID, accrualResp.Status)
-					if accrualResp.Accrual != nil && accrualResp.Status == "PROCESSED" {
-						_ = s.OrderRepo.AddBalanceTransaction(ctx, order.UserID, &order.ID, *accrualResp.Accrual, "ACCRUAL")
-					}
+					logger.Error("Ошибка чтения заказа из очереди", zap.Error(err))
+					time.Sleep(time.Second)
+					continue
+				}
+				order, err := s.OrderRepo.GetOrderByNumber(ctx, orderNumber)
+				if err != nil {
+					logger.Error("Заказ из очереди не найден в БД", zap.String("order", orderNumber), zap.Error(err))
+					_ = s.Queue.Ack(ctx, orderNumber)
+					continue
+				}
+				s.waitForThrottle(ctx)
+				s.processOrder(ctx, *order, requeue, logger)
+				_ = s.Queue.Ack(ctx, orderNumber)
+			}
+		}()
+	}
+}
+
+// processOrder опрашивает accrual-сервис по одному заказу с экспоненциальным backoff
+// на транзиентные 5xx ошибки. Статусы REGISTERED/PROCESSING передаются в requeue, чтобы
+// заказ был перепроверен позже с джиттером, а не ждал общего тикера. Возвращает true, если
+// requeue был вызван - тогда вызывающий (runOrderWorker) не снимает отметку inFlightOrders,
+// т.к. на заказ ещё запланирована повторная попытка (в том числе после исчерпания попыток);
+// false означает, что заказ дошёл до терминального статуса или ctx отменён, и отметку пора снять.
+func (s *OrderService) processOrder(ctx context.Context, order models.Order, requeue func(models.Order), logger *zap.Logger) bool {
+	backoff := 500 * time.Millisecond
+	for attempt := 1; attempt <= maxOrderAttempts; attempt++ {
+		orderCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		status, err := s.AccrualClient.GetOrder(orderCtx, order.OrderNumber)
+		cancel()
+		if err != nil {
+			logger.Error("Ошибка запроса к accrual-сервису",
+				zap.String("order", order.OrderNumber), zap.Int("attempt", attempt), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			continue
+		}
+
+		if status.RetryAfter > 0 {
+			s.throttleUntil(status.RetryAfter)
+		}
+
+		switch status.Status {
+		case orderStatusRegistered, orderStatusProcessing, "":
+			_ = s.OrderRepo.UpdateOrderStatus(ctx, order.ID, orderStatusRegistered)
+			s.invalidateUserCache(ctx, order.UserID)
+			requeue(order)
+			return true
+		case orderStatusInvalid:
+			_ = s.OrderRepo.UpdateOrderStatus(ctx, order.ID, orderStatusInvalid)
+			s.invalidateUserCache(ctx, order.UserID)
+			return false
+		case orderStatusProcessed:
+			if status.Accrual != nil {
+				orderID := order.ID
+				if err := s.OrderRepo.PostPostings(ctx, []models.Posting{
+					{AccountName: models.WorldAccountName, Amount: -*status.Accrual, OrderID: &orderID},
+					{AccountName: models.UserAccountName(order.UserID), Amount: *status.Accrual, OrderID: &orderID},
+				}); err != nil {
+					logger.Error("Не удалось провести начисление, заказ останется на опросе",
+						zap.String("order", order.OrderNumber), zap.Error(err))
+					requeue(order)
+					return true
 				}
 			}
+			_ = s.OrderRepo.UpdateOrderStatus(ctx, order.ID, orderStatusProcessed)
+			s.invalidateUserCache(ctx, order.UserID)
+			return false
+		default:
+			logger.Error("Неизвестный статус accrual-сервиса", zap.String("order", order.OrderNumber), zap.String("status", status.Status))
+			return false
 		}
-	}()
+	}
+	logger.Error("Исчерпаны попытки обращения к accrual-сервису, заказ будет перепроверен позже",
+		zap.String("order", order.OrderNumber))
+	requeue(order)
+	return true
 }
 
-func (s *OrderService) GetOrderAccrual(ctx context.Context, orderID int64) (*float64, error) {
-	return s.OrderRepo.GetOrderAccrual(ctx, orderID)
+// invalidateUserCache сбрасывает кэшированные orders/balance пользователя после того, как
+// воркер поменял статус заказа или провёл начисление - без этого GetOrdersHandler мог бы
+// отдавать устаревший статус до истечения cacheTTL.
+func (s *OrderService) invalidateUserCache(ctx context.Context, userID int64) {
+	if s.Cache == nil {
+		return
+	}
+	_ = s.Cache.Del(ctx, ordersCacheKey(userID), balanceCacheKey(userID))
+}
+
+func (s *OrderService) throttleUntil(d time.Duration) {
+	s.throttleMu.Lock()
+	defer s.throttleMu.Unlock()
+	until := time.Now().Add(d)
+	if until.After(s.throttledUntil) {
+		s.throttledUntil = until
+	}
+}
+
+// waitForThrottle блокирует воркер, если accrual-сервис попросил подождать через 429 Retry-After.
+func (s *OrderService) waitForThrottle(ctx context.Context) {
+	for {
+		s.throttleMu.Lock()
+		wait := time.Until(s.throttledUntil)
+		s.throttleMu.Unlock()
+		if wait <= 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
 }
 
 func (s *OrderService) GetUserBalance(ctx context.Context, userID int64) (current float64, withdrawn float64, err error) {
@@ -165,27 +428,28 @@ func (s *OrderService) WithdrawBalance(ctx context.Context, userID int64, orderN
 	if !isValidLuhn(orderNumber) {
 		return ErrInvalidOrderNumber
 	}
-	current, _, err := s.GetUserBalance(ctx, userID)
+	err := s.OrderRepo.WithdrawAtomic(ctx, userID, orderNumber, sum)
 	if err != nil {
-		return err
-	}
-	if sum > current {
-		return ErrInsufficientFunds
-	}
-	order, err := s.OrderRepo.GetOrderByNumber(ctx, orderNumber)
-	if err != nil || order == nil {
-		err = s.OrderRepo.CreateOrder(ctx, orderNumber, userID)
-		if err != nil {
-			return err
-		}
-		order, err = s.OrderRepo.GetOrderByNumber(ctx, orderNumber)
-		if err != nil {
-			return err
+		if errors.Is(err, models.ErrInsufficientFunds) {
+			return ErrInsufficientFunds
 		}
+		return err
 	}
-	return s.OrderRepo.AddBalanceTransaction(ctx, userID, &order.ID, sum, "WITHDRAWAL")
+	return nil
 }
 
 func (s *OrderService) GetUserWithdrawals(ctx context.Context, userID int64) ([]models.WithdrawalResponse, error) {
 	return s.OrderRepo.GetUserWithdrawals(ctx, userID)
 }
+
+// GetUserLedgerTransactions возвращает проводки по основному счёту пользователя с id больше
+// afterID - клиент передаёт последний увиденный id, чтобы постранично читать историю.
+func (s *OrderService) GetUserLedgerTransactions(ctx context.Context, userID int64, afterID int64, limit int) ([]models.LedgerTransaction, error) {
+	return s.OrderRepo.GetLedgerTransactions(ctx, models.UserAccountName(userID), afterID, limit)
+}
+
+// GetAccountBalance возвращает баланс произвольного именованного счёта ledger
+// (world, user:<id>:main, merchant:<orderNumber>).
+func (s *OrderService) GetAccountBalance(ctx context.Context, accountName string) (float64, error) {
+	return s.OrderRepo.GetAccountBalance(ctx, accountName)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	wan "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/auth"
+	"github.com/AlexeySalamakhin/gophermart/cmd/gophermart/models"
+)
+
+// challengeTTL - время жизни записи в webauthn_sessions между begin и finish одного
+// регистрационного/логин-потока.
+const challengeTTL = 5 * time.Minute
+
+var ErrNoWebAuthnCredentials = errors.New("у пользователя нет зарегистрированных ключей доступа")
+
+type WebAuthnRepo interface {
+	CreateCredential(ctx context.Context, cred models.WebAuthnCredential) error
+	GetCredentialsByUserID(ctx context.Context, userID int64) ([]models.WebAuthnCredential, error)
+	GetCredentialByCredentialID(ctx context.Context, credentialID []byte) (*models.WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) error
+	SaveChallenge(ctx context.Context, sessionKey, login string, data []byte, expiresAt time.Time) error
+	GetChallenge(ctx context.Context, sessionKey string) (login string, data []byte, err error)
+	DeleteChallenge(ctx context.Context, sessionKey string) error
+}
+
+// WebAuthnService оборачивает github.com/go-webauthn/webauthn, давая пользователям
+// passkey-альтернативу паролю - challenge'и регистрации/логина хранятся в WebAuthnRepo,
+// а не в памяти процесса, чтобы работать за несколькими репликами gophermart.
+type WebAuthnService struct {
+	WebAuthn     *wan.WebAuthn
+	WebAuthnRepo WebAuthnRepo
+	UserRepo     UserRepo
+}
+
+func NewWebAuthnService(rpID, rpDisplayName string, rpOrigins []string, repo WebAuthnRepo, userRepo UserRepo) (*WebAuthnService, error) {
+	w, err := wan.New(&wan.Config{
+		RPDisplayName: rpDisplayName,
+		RPID:          rpID,
+		RPOrigins:     rpOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &WebAuthnService{WebAuthn: w, WebAuthnRepo: repo, UserRepo: userRepo}, nil
+}
+
+// webauthnUser адаптирует models.User и его credentials к интерфейсу wan.User.
+type webauthnUser struct {
+	user        *models.User
+	credentials []models.WebAuthnCredential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte { return []byte(fmt.Sprintf("%d", u.user.ID)) }
+func (u *webauthnUser) WebAuthnName() string { return u.user.Login }
+func (u *webauthnUser) WebAuthnDisplayName() string { return u.user.Login }
+
+func (u *webauthnUser) WebAuthnCredentials() []wan.Credential {
+	creds := make([]wan.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		creds[i] = wan.Credential{
+			ID:        c.CredentialID,
+			PublicKey: c.PublicKey,
+			Authenticator: wan.Authenticator{
+				SignCount: c.SignCount,
+				AAGUID:    c.AAGUID,
+			},
+		}
+	}
+	return creds
+}
+
+func (s *WebAuthnService) loadUser(ctx context.Context, login string) (*webauthnUser, error) {
+	user, err := s.UserRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	creds, err := s.WebAuthnRepo.GetCredentialsByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webauthnUser{user: user, credentials: creds}, nil
+}
+
+func (s *WebAuthnService) saveChallenge(ctx context.Context, login string, sessionData any) (string, error) {
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		return "", err
+	}
+	sessionKey := uuid.NewString()
+	if err := s.WebAuthnRepo.SaveChallenge(ctx, sessionKey, login, data, time.Now().Add(challengeTTL)); err != nil {
+		return "", err
+	}
+	return sessionKey, nil
+}
+
+func (s *WebAuthnService) loadChallenge(ctx context.Context, sessionKey string) (string, wan.SessionData, error) {
+	var sessionData wan.SessionData
+	login, raw, err := s.WebAuthnRepo.GetChallenge(ctx, sessionKey)
+	if err != nil {
+		return "", sessionData, err
+	}
+	if err := json.Unmarshal(raw, &sessionData); err != nil {
+		return "", sessionData, err
+	}
+	return login, sessionData, nil
+}
+
+// BeginRegistration начинает привязку нового passkey к уже аутентифицированному пользователю.
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, login string) (*protocol.CredentialCreation, string, error) {
+	user, err := s.loadUser(ctx, login)
+	if err != nil {
+		return nil, "", err
+	}
+	options, sessionData, err := s.WebAuthn.BeginRegistration(user)
+	if err != nil {
+		return nil, "", err
+	}
+	sessionKey, err := s.saveChallenge(ctx, login, sessionData)
+	if err != nil {
+		return nil, "", err
+	}
+	return options, sessionKey, nil
+}
+
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, sessionKey string, r *http.Request) error {
+	login, sessionData, err := s.loadChallenge(ctx, sessionKey)
+	if err != nil {
+		return err
+	}
+	defer s.WebAuthnRepo.DeleteChallenge(ctx, sessionKey)
+
+	user, err := s.loadUser(ctx, login)
+	if err != nil {
+		return err
+	}
+	credential, err := s.WebAuthn.FinishRegistration(user, sessionData, r)
+	if err != nil {
+		return err
+	}
+	return s.WebAuthnRepo.CreateCredential(ctx, models.WebAuthnCredential{
+		UserID:       user.user.ID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.Authenticator.SignCount,
+		AAGUID:       credential.Authenticator.AAGUID,
+	})
+}
+
+// BeginLogin начинает пассwordless-вход по логину: возвращает challenge с разрешёнными
+// credential ID, сохранёнными до этого через FinishRegistration.
+func (s *WebAuthnService) BeginLogin(ctx context.Context, login string) (*protocol.CredentialAssertion, string, error) {
+	user, err := s.loadUser(ctx, login)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(user.credentials) == 0 {
+		return nil, "", ErrNoWebAuthnCredentials
+	}
+	options, sessionData, err := s.WebAuthn.BeginLogin(user)
+	if err != nil {
+		return nil, "", err
+	}
+	sessionKey, err := s.saveChallenge(ctx, login, sessionData)
+	if err != nil {
+		return nil, "", err
+	}
+	return options, sessionKey, nil
+}
+
+// FinishLogin проверяет подпись/sign_count ассерции и выдаёт тот же JWT-cookie, что и пароль.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, sessionKey string, r *http.Request) (string, error) {
+	login, sessionData, err := s.loadChallenge(ctx, sessionKey)
+	if err != nil {
+		return "", err
+	}
+	defer s.WebAuthnRepo.DeleteChallenge(ctx, sessionKey)
+
+	user, err := s.loadUser(ctx, login)
+	if err != nil {
+		return "", err
+	}
+	credential, err := s.WebAuthn.FinishLogin(user, sessionData, r)
+	if err != nil {
+		return "", err
+	}
+	if err := s.WebAuthnRepo.UpdateSignCount(ctx, credential.ID, credential.Authenticator.SignCount); err != nil {
+		return "", err
+	}
+	return auth.GenerateJWT(login)
+}